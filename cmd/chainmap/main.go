@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ihsanlearn/chainmap/logger"
 	"github.com/ihsanlearn/chainmap/options"
@@ -9,13 +12,61 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			exitOnError(runQuery(os.Args[2:]))
+			return
+		case "merge", "report":
+			exitOnError(runReport(os.Args[2:]))
+			return
+		case "serve":
+			exitOnError(runServe(os.Args[2:]))
+			return
+		case "worker":
+			exitOnError(runWorker(os.Args[2:]))
+			return
+		case runner.SandboxCommand:
+			exitOnError(runSandboxExec(os.Args[2:]))
+			return
+		}
+	}
+
 	opts := options.ParseOptions()
+	defer logger.Close()
 	r := runner.New(opts)
 
 	if err := r.CheckDependencies(); err != nil {
 		logger.Error("System check failed: %s", err)
 		os.Exit(1)
 	}
+	exitOnError(dropPrivileges(opts))
+	if opts.Coordinator != "" && opts.ClusterToken == "" && !isLoopback(opts.Coordinator) {
+		logger.Warn("Coordinator listening on %s without -cluster-token; anyone who can reach it can take jobs", opts.Coordinator)
+	}
 
-	r.Run()
+	if opts.QueueIn != "" {
+		// Queue mode runs until interrupted and then drains cleanly.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		exitOnError(r.RunContext(ctx))
+		return
+	}
+
+	exitOnError(r.Run())
+}
+
+// runReport implements "chainmap merge" and "chainmap report".
+func runReport(args []string) error {
+	opts, paths := options.ParseReportOptions(args)
+	defer logger.Close()
+	return runner.New(opts).Report(paths)
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		logger.Error("%s", err)
+		logger.Close()
+		os.Exit(1)
+	}
 }
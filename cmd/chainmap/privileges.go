@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+	"github.com/ihsanlearn/chainmap/pkg/report"
+	"github.com/ihsanlearn/chainmap/pkg/runner"
+)
+
+// runAsEnv marks the chainmap process relaunched by -run-as.
+const runAsEnv = "CHAINMAP_RUN_AS"
+
+// dropPrivileges hands the run to opts.RunAs when chainmap was started as
+// root. The root process creates the directories the run writes to for
+// that user and checks that files it would overwrite are already the
+// user's. It then relaunches chainmap as the user with only CAP_NET_RAW
+// and CAP_NET_ADMIN and exits with its status. It returns in the
+// relaunched process and when there is nothing to drop.
+func dropPrivileges(opts *options.Options) error {
+	if opts.RunAs == "" || os.Getenv(runAsEnv) != "" {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		logger.Warn("Ignoring -run-as %s, chainmap is not running as root", opts.RunAs)
+		return nil
+	}
+	u, err := privileges.LookupUser(opts.RunAs)
+	if err != nil {
+		return err
+	}
+	files := []string{opts.Database, opts.PlanOutput}
+	if opts.SummaryJSON != "-" {
+		files = append(files, opts.SummaryJSON)
+	}
+	// Invalid -o values are reported by the relaunched chainmap. Relative
+	// ones end up inside -output-dir.
+	if outputs, err := report.ParseOutputs(opts.Outputs, nil); err == nil {
+		for _, out := range outputs {
+			if opts.OutputDir == "" || filepath.IsAbs(out.Path) {
+				files = append(files, out.Path)
+			}
+		}
+	}
+	// The log file is already open; it is only handed over when this
+	// process created it.
+	if opts.LogFile != "" {
+		if logger.CreatedLogFile() {
+			if err := u.Chown(opts.LogFile); err != nil {
+				return fmt.Errorf("failed to hand %s to %s: %w", opts.LogFile, u.Name, err)
+			}
+		} else {
+			files = append(files, opts.LogFile)
+		}
+	}
+	if err := u.Claim([]string{opts.StateDir, opts.OutputDir}, files); err != nil {
+		return fmt.Errorf("cannot run as %s: %w", u.Name, err)
+	}
+	// Service accounts often have no home, where goflags keeps its config.
+	if fi, err := os.Stat(u.Home); err != nil || !fi.IsDir() {
+		u.Home, _ = filepath.Abs(opts.StateDir)
+	}
+
+	cmd, err := u.Command(os.Args[1:]...)
+	if err != nil {
+		return err
+	}
+	cmd.Env = append(cmd.Env, runAsEnv+"="+u.Name)
+	logger.Info("Dropping root, continuing as %s with CAP_NET_RAW and CAP_NET_ADMIN", u.Name)
+
+	// Ctrl-C reaches the whole process group, so only the relaunched
+	// chainmap handles it; SIGTERM is passed on.
+	signal.Ignore(os.Interrupt)
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGTERM)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to relaunch as %s: %w", u.Name, err)
+	}
+	go func() {
+		for sig := range sigs {
+			cmd.Process.Signal(sig)
+		}
+	}()
+	cmd.Wait()
+	logger.Close()
+	os.Exit(cmd.ProcessState.ExitCode())
+	return nil
+}
+
+// runSandboxExec implements the hidden "sandbox-exec <dir> -- nmap <args>"
+// command that -sandbox starts every nmap through.
+func runSandboxExec(args []string) error {
+	if len(args) < 3 || args[1] != "--" {
+		return fmt.Errorf("usage: chainmap %s <dir> -- <command> [args]", runner.SandboxCommand)
+	}
+	return privileges.SandboxExec(args[0], args[2:])
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/store"
+)
+
+// runQuery implements "chainmap query".
+func runQuery(args []string) error {
+	opts := options.ParseQueryOptions(args)
+
+	if _, err := os.Stat(opts.Database); err != nil {
+		return fmt.Errorf("cannot open database: %w", err)
+	}
+	db, err := store.Open(opts.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var res *store.Result
+	switch {
+	case opts.SQL != "":
+		res, err = db.Raw(opts.SQL)
+	case opts.Port > 0:
+		res, err = db.OpenPort(opts.Port, opts.Latest)
+	case opts.Product != "":
+		res, err = db.ProductBelow(opts.Product, opts.Below, opts.Latest)
+	case opts.Service != "":
+		res, err = db.Service(opts.Service, opts.Latest)
+	default:
+		res, err = db.Scans()
+	}
+	if err != nil {
+		return fmt.Errorf("query failed: %w", err)
+	}
+
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(res.Columns, "\t")))
+	for _, row := range res.Rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
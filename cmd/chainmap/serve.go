@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/runner"
+	"github.com/ihsanlearn/chainmap/pkg/server"
+)
+
+// runServe implements "chainmap serve".
+func runServe(args []string) error {
+	opts := options.ParseServeOptions(args)
+	defer logger.Close()
+
+	if err := runner.New(&opts.Options).CheckDependencies(); err != nil {
+		return err
+	}
+	if opts.Token == "" && !isLoopback(opts.Addr) {
+		logger.Warn("Listening on %s without -token; anyone who can reach it can run scans", opts.Addr)
+	}
+
+	srv, err := server.New(server.Config{
+		Addr:             opts.Addr,
+		DataDir:          opts.DataDir,
+		Token:            opts.Token,
+		MaxScans:         opts.MaxScans,
+		MaxThreads:       opts.MaxThreads,
+		MaxTimeout:       opts.MaxTimeout,
+		AllowCustomFlags: opts.AllowCustomFlags,
+		FlagPolicy:       opts.FlagPolicy,
+		Metrics:          opts.Metrics,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return srv.ListenAndServe(ctx)
+}
+
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/runner"
+)
+
+// runWorker implements "chainmap worker".
+func runWorker(args []string) error {
+	opts := options.ParseWorkerOptions(args)
+	defer logger.Close()
+
+	if err := runner.New(&opts.Options).CheckDependencies(); err != nil {
+		return err
+	}
+	if err := dropPrivileges(&opts.Options); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return runner.NewWorker(&opts.Options, opts.CoordinatorURL, opts.ClusterToken, opts.Name).Run(ctx)
+}
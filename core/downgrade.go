@@ -0,0 +1,195 @@
+package core
+
+import "strings"
+
+// FlagChange records an nmap flag rewritten to run without raw socket
+// access. To is empty when the flag was dropped.
+type FlagChange struct {
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (c FlagChange) String() string {
+	if c.To == "" {
+		return c.From + " dropped (" + c.Reason + ")"
+	}
+	return c.From + " -> " + c.To + " (" + c.Reason + ")"
+}
+
+// rawScanTypes maps the letters of -s scan types that need raw sockets to
+// their unprivileged replacement, or to "" when there is none.
+var rawScanTypes = map[byte]string{
+	'S': "T", 'A': "T", 'W': "T", 'M': "T", 'N': "T", 'F': "T", 'X': "T",
+	'U': "", 'Y': "", 'Z': "", 'O': "", 'I': "",
+}
+
+// rawFlags are flags that need raw sockets and have no unprivileged
+// equivalent, with whether they take a value.
+var rawFlags = map[string]bool{
+	"-O":             false,
+	"--osscan-guess": false,
+	"--osscan-limit": false,
+	"--fuzzy":        false,
+	"--traceroute":   false,
+	"-f":             false,
+	"--badsum":       false,
+	"--send-eth":     false,
+	"--send-ip":      false,
+	"--privileged":   false,
+	"--mtu":          true,
+	"--ttl":          true,
+	"--ip-options":   true,
+	"--spoof-mac":    true,
+	"--data":         true,
+	"--data-string":  true,
+	"--data-length":  true,
+	"-S":             true,
+	"-D":             true,
+	"--scanflags":    true,
+	"--max-os-tries": true,
+	"--source-port":  true,
+	"-g":             true,
+}
+
+// rawPingProbes are host discovery probes sent over raw sockets. The TCP
+// probes -PS and -PA fall back to connect() and are kept.
+var rawPingProbes = []string{"-PE", "-PP", "-PM", "-PU", "-PY", "-PO", "-PR"}
+
+// DowngradeFlags rewrites nmap arguments that need root or CAP_NET_RAW into
+// their unprivileged equivalents: SYN and other raw TCP scans become connect
+// scans (-sT), -A becomes -sV -sC, and OS detection, UDP/SCTP scans, raw
+// ping probes and packet crafting options are dropped. It returns the new
+// arguments and every change made, which is empty when args already run
+// unprivileged.
+func DowngradeFlags(args []string) ([]string, []FlagChange) {
+	var out []string
+	var changes []FlagChange
+	seen := make(map[string]bool)
+	add := func(flags ...string) {
+		for _, f := range flags {
+			// Several raw scan types collapse into one -sT.
+			if f == "-sT" && seen[f] {
+				continue
+			}
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-A":
+			add("-sV", "-sC")
+			changes = append(changes, FlagChange{From: arg, To: "-sV -sC", Reason: "OS detection and traceroute need raw sockets"})
+
+		case isScanType(arg):
+			letters, changed := downgradeScanType(arg[2:])
+			if !changed {
+				add(arg)
+				continue
+			}
+			change := FlagChange{From: arg, Reason: "raw socket scan"}
+			if letters != "" {
+				change.To = "-s" + letters
+				add(change.To)
+			}
+			changes = append(changes, change)
+			// The idle scan takes its zombie host as the next argument.
+			if strings.Contains(arg[2:], "I") && i+1 < len(args) {
+				i++
+				changes[len(changes)-1].From += " " + args[i]
+			}
+
+		case rawPingProbe(arg):
+			changes = append(changes, FlagChange{From: arg, Reason: "raw socket ping probe"})
+
+		default:
+			name, _, inline := strings.Cut(arg, "=")
+			takesValue, raw := rawFlags[name]
+			if !raw {
+				add(arg)
+				continue
+			}
+			from := arg
+			if takesValue && !inline && i+1 < len(args) {
+				i++
+				from += " " + args[i]
+			}
+			changes = append(changes, FlagChange{From: from, Reason: rawFlagReason(name)})
+		}
+	}
+	return out, changes
+}
+
+// isScanType reports whether arg selects scan types, like -sS or -sSV.
+func isScanType(arg string) bool {
+	if !strings.HasPrefix(arg, "-s") || len(arg) < 3 {
+		return false
+	}
+	for i := 2; i < len(arg); i++ {
+		if !strings.ContainsRune("SAWMNFXUYZOITVCLPn", rune(arg[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// downgradeScanType replaces the scan type letters that need raw sockets
+// and reports whether any did.
+func downgradeScanType(letters string) (string, bool) {
+	changed := false
+	var kept []byte
+	for i := 0; i < len(letters); i++ {
+		c := letters[i]
+		to, raw := rawScanTypes[c]
+		if raw {
+			changed = true
+		} else {
+			to = string(c)
+		}
+		if to != "" && !strings.Contains(string(kept), to) {
+			kept = append(kept, to...)
+		}
+	}
+	return string(kept), changed
+}
+
+func rawPingProbe(arg string) bool {
+	for _, p := range rawPingProbes {
+		if strings.HasPrefix(arg, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func rawFlagReason(name string) string {
+	switch name {
+	case "-O", "--osscan-guess", "--osscan-limit", "--fuzzy", "--max-os-tries":
+		return "OS detection needs raw sockets"
+	case "--traceroute":
+		return "traceroute needs raw sockets"
+	case "--privileged":
+		return "raw socket access is not available"
+	default:
+		return "packet crafting needs raw sockets"
+	}
+}
+
+// FlagChanges returns the distinct flag changes recorded in records, in
+// the order they first appear.
+func FlagChanges(records []JobRecord) []FlagChange {
+	var changes []FlagChange
+	seen := make(map[string]bool)
+	for _, rec := range records {
+		for _, c := range rec.Downgraded {
+			if key := c.String(); !seen[key] {
+				seen[key] = true
+				changes = append(changes, c)
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDowngradeFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   string
+		want    string
+		changed []string
+	}{
+		{"Unprivileged", "-sT -sV -T4 -Pn -n", "-sT -sV -T4 -Pn -n", nil},
+		{"SYN scan", "-sV -sS -T3 -Pn -n --host-timeout 5m", "-sV -sT -T3 -Pn -n --host-timeout 5m", []string{"-sS"}},
+		{"Combined scan types", "-sSV -T4", "-sTV -T4", []string{"-sSV"}},
+		{"Several raw scans", "-sS -sA -sT", "-sT", []string{"-sS", "-sA"}},
+		{"UDP dropped", "-sS -sU -p T:22,U:53", "-sT -p T:22,U:53", []string{"-sS", "-sU"}},
+		{"Aggressive", "-A -T4", "-sV -sC -T4", []string{"-A"}},
+		{"OS detection", "-sV -O --osscan-guess", "-sV", []string{"-O", "--osscan-guess"}},
+		{"Values dropped", "-sV --mtu 24 --ttl=64 -D RND:5", "-sV", []string{"--mtu 24", "--ttl=64", "-D RND:5"}},
+		{"Idle scan", "-sI zombie.example -Pn", "-Pn", []string{"-sI zombie.example"}},
+		{"Ping probes", "-PE -PS22,80 -PU53 -sn", "-PS22,80 -sn", []string{"-PE", "-PU53"}},
+		{"Privileged", "--privileged -sS", "-sT", []string{"--privileged", "-sS"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes := DowngradeFlags(strings.Fields(tt.flags))
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("DowngradeFlags() = %q, want %q", strings.Join(got, " "), tt.want)
+			}
+			var from []string
+			for _, c := range changes {
+				from = append(from, c.From)
+			}
+			if !reflect.DeepEqual(from, tt.changed) {
+				t.Errorf("changed %q, want %q", from, tt.changed)
+			}
+		})
+	}
+}
+
+func TestFlagChanges(t *testing.T) {
+	syn := FlagChange{From: "-sS", To: "-sT", Reason: "raw socket scan"}
+	osScan := FlagChange{From: "-O", Reason: "OS detection needs raw sockets"}
+	records := []JobRecord{
+		{Downgraded: []FlagChange{syn}},
+		{},
+		{Downgraded: []FlagChange{syn, osScan}},
+	}
+	want := []FlagChange{syn, osScan}
+	if got := FlagChanges(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlagChanges() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,484 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+// Filter selects ports and hosts from scan results. It is parsed from
+// expressions such as `port in (22,3389) and service ~ http`; see
+// ParseFilter for the syntax.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+type filterNode interface {
+	match(host *nmap.Host, port *nmap.Port) bool
+}
+
+type andNode []filterNode
+
+func (n andNode) match(h *nmap.Host, p *nmap.Port) bool {
+	for _, c := range n {
+		if !c.match(h, p) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []filterNode
+
+func (n orNode) match(h *nmap.Host, p *nmap.Port) bool {
+	for _, c := range n {
+		if c.match(h, p) {
+			return true
+		}
+	}
+	return false
+}
+
+type notNode struct{ node filterNode }
+
+func (n notNode) match(h *nmap.Host, p *nmap.Port) bool {
+	return !n.node.match(h, p)
+}
+
+// condition compares one field against one or more values.
+type condition struct {
+	field  string
+	op     string
+	values []string
+	re     *regexp.Regexp
+}
+
+// filterFields lists the fields a condition can test and the operators
+// each one accepts.
+var filterFields = map[string][]string{
+	"port":     {"==", "!=", "<", "<=", ">", ">=", "in"},
+	"protocol": {"==", "!=", "~", "!~", "in", "contains"},
+	"state":    {"==", "!=", "~", "!~", "in", "contains"},
+	"service":  {"==", "!=", "~", "!~", "in", "contains"},
+	"product":  {"==", "!=", "~", "!~", "in", "contains"},
+	"version":  {"==", "!=", "~", "!~", "in", "contains"},
+	"host":     {"==", "!=", "~", "!~", "in", "contains"},
+	"script":   {"exists", "==", "~", "contains"},
+}
+
+func (c *condition) match(h *nmap.Host, p *nmap.Port) bool {
+	switch c.field {
+	case "host":
+		values := []string{}
+		for _, a := range h.Addresses {
+			values = append(values, a.Addr)
+		}
+		for _, hn := range h.Hostnames {
+			values = append(values, hn.Name)
+		}
+		if c.op == "!=" || c.op == "!~" {
+			for _, v := range values {
+				if !c.compare(v) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, v := range values {
+			if c.compare(v) {
+				return true
+			}
+		}
+		return false
+	case "script":
+		var scripts []nmap.Script
+		if p != nil {
+			scripts = append(scripts, p.Scripts...)
+		}
+		scripts = append(scripts, h.HostScripts...)
+		for _, s := range scripts {
+			switch c.op {
+			case "exists", "==":
+				if strings.EqualFold(s.Id, c.values[0]) {
+					return true
+				}
+			case "~":
+				if c.re.MatchString(s.Output) {
+					return true
+				}
+			case "contains":
+				if containsFold(s.Output, c.values[0]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	// The remaining fields belong to a port; hosts without ports never match.
+	if p == nil {
+		return false
+	}
+	if c.field == "port" {
+		return c.comparePort(p.PortId)
+	}
+
+	var value string
+	switch c.field {
+	case "protocol":
+		value = p.Protocol
+	case "state":
+		value = p.State.State
+	case "service":
+		value = p.Service.Name
+	case "product":
+		value = p.Service.Product
+	case "version":
+		value = p.Service.Version
+	}
+	return c.compare(value)
+}
+
+func (c *condition) compare(value string) bool {
+	switch c.op {
+	case "==":
+		return strings.EqualFold(value, c.values[0])
+	case "!=":
+		return !strings.EqualFold(value, c.values[0])
+	case "~":
+		return c.re.MatchString(value)
+	case "!~":
+		return !c.re.MatchString(value)
+	case "contains":
+		return containsFold(value, c.values[0])
+	case "in":
+		for _, v := range c.values {
+			if strings.EqualFold(value, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *condition) comparePort(port int) bool {
+	for _, v := range c.values {
+		n, _ := strconv.Atoi(v)
+		switch c.op {
+		case "==", "in":
+			if port == n {
+				return true
+			}
+		case "!=":
+			return port != n
+		case "<":
+			return port < n
+		case "<=":
+			return port <= n
+		case ">":
+			return port > n
+		case ">=":
+			return port >= n
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// ParseFilter parses a filter expression. Conditions have the form
+// `field op value` and can be combined with and, or, not and parentheses.
+//
+//	port in (22,3389)
+//	service ~ http and state == open
+//	script exists vulners or product contains OpenSSH
+//
+// String comparisons and regular expressions are case-insensitive. Values
+// containing spaces or parentheses can be quoted.
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty filter")
+	}
+
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q in filter", tok.text)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether port on host passes the filter. port is nil for
+// hosts without ports.
+func (f *Filter) Match(host *nmap.Host, port *nmap.Port) bool {
+	return f.root.match(host, port)
+}
+
+// Apply returns a copy of run holding only the ports that pass the filter
+// and the hosts left with at least one of them. Hosts without ports are kept
+// when they match on their own, e.g. for `host == 10.0.0.1`. The host counts
+// in RunStats are those of the hosts kept.
+func (f *Filter) Apply(run *nmap.NmapRun) *nmap.NmapRun {
+	filtered := *run
+	filtered.Hosts = nil
+
+	for i := range run.Hosts {
+		host := run.Hosts[i]
+		if len(host.Ports) == 0 {
+			if f.Match(&host, nil) {
+				filtered.Hosts = append(filtered.Hosts, host)
+			}
+			continue
+		}
+
+		var ports []nmap.Port
+		for j := range host.Ports {
+			if f.Match(&host, &host.Ports[j]) {
+				ports = append(ports, host.Ports[j])
+			}
+		}
+		if len(ports) > 0 {
+			host.Ports = ports
+			filtered.Hosts = append(filtered.Hosts, host)
+		}
+	}
+
+	filtered.RunStats.Hosts.Up, filtered.RunStats.Hosts.Down = 0, 0
+	for _, host := range filtered.Hosts {
+		if host.Status.State == "up" {
+			filtered.RunStats.Hosts.Up++
+		} else {
+			filtered.RunStats.Hosts.Down++
+		}
+	}
+	filtered.RunStats.Hosts.Total = len(filtered.Hosts)
+	return &filtered
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	r := []rune(expr)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')' || c == ',':
+			tokens = append(tokens, filterToken{text: string(c)})
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(r) && r[end] != c {
+				end++
+			}
+			if end == len(r) {
+				return nil, fmt.Errorf("unterminated quote in filter")
+			}
+			tokens = append(tokens, filterToken{text: string(r[i+1 : end]), quoted: true})
+			i = end + 1
+		case strings.ContainsRune("=!~<>", c):
+			end := i + 1
+			for end < len(r) && strings.ContainsRune("=~", r[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{text: string(r[i:end])})
+			i = end
+		default:
+			end := i
+			for end < len(r) && !unicode.IsSpace(r[end]) && !strings.ContainsRune("(),=!~<>\"'", r[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{text: string(r[i:end])})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return tok, fmt.Errorf("unexpected end of filter")
+	}
+	p.pos++
+	return tok, nil
+}
+
+// keyword consumes the next token if it is the unquoted keyword kw.
+func (p *filterParser) keyword(kw string) bool {
+	tok, ok := p.peek()
+	if ok && !tok.quoted && strings.EqualFold(tok.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	var nodes orNode
+	for {
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.keyword("or") {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	var nodes andNode
+	for {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if !p.keyword("and") {
+			break
+		}
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return nodes, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.keyword("not") {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	if p.keyword("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("missing ) in filter")
+		}
+		return n, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (filterNode, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	field := strings.ToLower(tok.text)
+	ops, ok := filterFields[field]
+	if !ok || tok.quoted {
+		return nil, fmt.Errorf("unknown filter field %q", tok.text)
+	}
+
+	tok, err = p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(tok.text)
+	if !containsString(ops, op) {
+		return nil, fmt.Errorf("operator %q is not supported for %s (use %s)", tok.text, field, strings.Join(ops, ", "))
+	}
+
+	c := &condition{field: field, op: op}
+	if op == "in" {
+		if c.values, err = p.parseList(); err != nil {
+			return nil, err
+		}
+	} else {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		c.values = []string{tok.text}
+	}
+
+	if field == "port" {
+		for _, v := range c.values {
+			if _, err := strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("invalid port %q in filter", v)
+			}
+		}
+	}
+	if op == "~" || op == "!~" {
+		if c.re, err = regexp.Compile("(?i)" + c.values[0]); err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", c.values[0], err)
+		}
+	}
+	return c, nil
+}
+
+func (p *filterParser) parseList() ([]string, error) {
+	if !p.keyword("(") {
+		return nil, fmt.Errorf("expected ( after in")
+	}
+	var values []string
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, tok.text)
+		if p.keyword(")") {
+			return values, nil
+		}
+		if !p.keyword(",") {
+			return nil, fmt.Errorf("expected , or ) in list")
+		}
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
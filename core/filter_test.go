@@ -0,0 +1,129 @@
+package core
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+func filterTestRun() *nmap.NmapRun {
+	run := &nmap.NmapRun{Hosts: []nmap.Host{
+		{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+			Ports: []nmap.Port{
+				{PortId: 22, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "ssh", Product: "OpenSSH", Version: "8.2p1"}},
+				{PortId: 80, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "http", Product: "Apache httpd"},
+					Scripts: []nmap.Script{{Id: "vulners", Output: "CVE-2021-41773"}}},
+				{PortId: 443, Protocol: "tcp", State: nmap.State{State: "closed"},
+					Service: nmap.Service{Name: "https"}},
+			},
+		},
+		{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.2"}},
+			Hostnames: []nmap.Hostname{{Name: "rdp.example"}},
+			Ports: []nmap.Port{
+				{PortId: 3389, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "ms-wbt-server"}},
+			},
+		},
+		{
+			Status:    nmap.Status{State: "down"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.3"}},
+		},
+	}}
+	run.RunStats.Hosts.Up, run.RunStats.Hosts.Down, run.RunStats.Hosts.Total = 2, 1, 3
+	return run
+}
+
+func TestFilterApply(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected []string
+	}{
+		{"port in (22,3389)", []string{"10.0.0.1:22", "10.0.0.2:3389"}},
+		{"service ~ http", []string{"10.0.0.1:80", "10.0.0.1:443"}},
+		{"service ~ http and state == open", []string{"10.0.0.1:80"}},
+		{"script exists vulners", []string{"10.0.0.1:80"}},
+		{"product contains openssh", []string{"10.0.0.1:22"}},
+		{"state != open", []string{"10.0.0.1:443"}},
+		{"port >= 443 and not port == 3389", []string{"10.0.0.1:443"}},
+		{"host == rdp.example or (port == 22)", []string{"10.0.0.1:22", "10.0.0.2:3389"}},
+		{"host == 10.0.0.3", []string{"10.0.0.3"}},
+		{`script contains "CVE-2021"`, []string{"10.0.0.1:80"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+
+			var got []string
+			for _, h := range f.Apply(filterTestRun()).Hosts {
+				if len(h.Ports) == 0 {
+					got = append(got, h.Addresses[0].Addr)
+				}
+				for _, p := range h.Ports {
+					got = append(got, h.Addresses[0].Addr+":"+strconv.Itoa(p.PortId))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Apply() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFilterApplyStats(t *testing.T) {
+	tests := []struct {
+		expr            string
+		up, down, total int
+	}{
+		{"port == 22", 1, 0, 1},
+		{"port in (22,3389)", 2, 0, 2},
+		{"host == 10.0.0.3", 0, 1, 1},
+		{"port == 8080", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseFilter() error = %v", err)
+			}
+			run := filterTestRun()
+			hosts := f.Apply(run).RunStats.Hosts
+			if hosts.Up != tt.up || hosts.Down != tt.down || hosts.Total != tt.total {
+				t.Errorf("Apply() hosts = %d up, %d down, %d total, want %d, %d, %d",
+					hosts.Up, hosts.Down, hosts.Total, tt.up, tt.down, tt.total)
+			}
+			if run.RunStats.Hosts.Total != 3 {
+				t.Errorf("Apply() changed the input stats")
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"color == red",
+		"port ~ 22",
+		"port == ssh",
+		"port in (22,",
+		"service ~ (",
+		"(state == open",
+		"state == open extra",
+	}
+	for _, expr := range tests {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) expected error", expr)
+		}
+	}
+}
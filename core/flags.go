@@ -0,0 +1,254 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlagPolicy restricts the nmap flags a scan may use, for shared scanner
+// hosts. Allow and Deny hold flag names or glob patterns such as "-T*"; a
+// non-empty Allow rejects every flag it does not match and Deny always
+// wins. NSE scripts and data files are only loaded from disk with
+// ScriptPaths.
+type FlagPolicy struct {
+	Allow       []string `yaml:"allow"`
+	Deny        []string `yaml:"deny"`
+	ScriptPaths bool     `yaml:"script_paths"`
+}
+
+// LoadFlagPolicy reads a YAML flag policy.
+func LoadFlagPolicy(filename string) (*FlagPolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var p FlagPolicy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	for _, pattern := range append(p.Allow, p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "-") {
+			return nil, fmt.Errorf("invalid flag pattern %q", pattern)
+		}
+	}
+	return &p, nil
+}
+
+// outputFlags are managed by chainmap, which writes XML to its own files.
+var outputFlags = map[string]bool{
+	"-o": true, "-oH": true, "-oN": true, "-oX": true, "-oS": true, "-oG": true, "-oA": true, "-oM": true,
+	"--stylesheet": true, "--webxml": true, "--no-stylesheet": true, "--append-output": true,
+}
+
+// rejectedFlags change which targets are scanned or do not scan at all.
+var rejectedFlags = map[string]string{
+	"-i":        "targets come from -t, -l or stdin",
+	"-iL":       "targets come from -t, -l or stdin",
+	"-iR":       "targets come from -t, -l or stdin",
+	"--resume":  "chainmap resumes with -rescan",
+	"-h":        "it does not scan",
+	"--help":    "it does not scan",
+	"-V":        "it does not scan",
+	"--version": "it does not scan",
+	"--iflist":  "it does not scan",
+}
+
+// pathFlags read files that can carry NSE code or change how nmap loads it.
+var pathFlags = map[string]bool{
+	"--script-args-file": true,
+	"--datadir":          true,
+	"--servicedb":        true,
+	"--versiondb":        true,
+}
+
+// valueFlags take their value as the next argument.
+var valueFlags = map[string]bool{
+	"-p": true, "-e": true, "-S": true, "-D": true, "-g": true, "-sI": true,
+	"-iL": true, "-iR": true, "--exclude": true, "--excludefile": true, "--exclude-ports": true,
+	"--top-ports": true, "--port-ratio": true, "--script": true, "--script-args": true,
+	"--script-args-file": true, "--script-timeout": true, "--script-help": true,
+	"--min-rate": true, "--max-rate": true, "--max-retries": true, "--host-timeout": true,
+	"--scan-delay": true, "--max-scan-delay": true, "--min-rtt-timeout": true,
+	"--max-rtt-timeout": true, "--initial-rtt-timeout": true, "--min-hostgroup": true,
+	"--max-hostgroup": true, "--min-parallelism": true, "--max-parallelism": true,
+	"--version-intensity": true, "--mtu": true, "--ttl": true, "--data": true,
+	"--data-string": true, "--data-length": true, "--ip-options": true, "--spoof-mac": true,
+	"--proxies": true, "--dns-servers": true, "--source-port": true, "--datadir": true,
+	"--servicedb": true, "--versiondb": true, "--resume": true, "--scanflags": true,
+	"--max-os-tries": true, "--stats-every": true, "--stylesheet": true,
+	"-o": true, "-i": true, "-oH": true,
+	"-oN": true, "-oX": true, "-oS": true, "-oG": true, "-oA": true, "-oM": true,
+}
+
+// longOptions are nmap's long options. nmap also accepts them with a single
+// dash and abbreviated to a unique prefix, so "-datad" means --datadir.
+// The two-letter options keep the single dash they are documented with.
+var longOptions = []string{
+	"iL", "iR", "sI", "oA", "oG", "oH", "oM", "oN", "oS", "oX", "rH", "vv", "ff",
+	"adler32", "allports", "append-output", "badsum", "data", "data-file", "data-length",
+	"data-string", "datadir", "debug", "defeat-icmp-ratelimit", "defeat-rst-ratelimit",
+	"deprecated-xml-osclass", "disable-arp-ping", "discovery-ignore-rst", "dns-servers",
+	"exclude", "exclude-ports", "excludefile", "fuzzy", "help", "host-timeout", "iflist",
+	"initial-rtt-timeout", "ip-options", "log-errors", "max-hostgroup", "max-os-tries",
+	"max-parallelism", "max-rate", "max-retries", "max-rtt-timeout", "max-scan-delay",
+	"min-hostgroup", "min-parallelism", "min-rate", "min-rtt-timeout", "mtu",
+	"no-stylesheet", "noninteractive", "nsock-engine", "open", "osscan-guess",
+	"osscan-limit", "packet-trace", "port-ratio", "privileged", "proxies", "proxy",
+	"randomize-hosts", "reason", "release-memory", "resolve-all", "resume", "route-dst",
+	"scan-delay", "scanflags", "script", "script-args", "script-args-file", "script-help",
+	"script-timeout", "script-trace", "script-updatedb", "send-eth", "send-ip",
+	"servicedb", "source-port", "spoof-mac", "stats-every", "stylesheet", "system-dns",
+	"thc", "timing", "top-ports", "traceroute", "ttl", "unique", "unprivileged",
+	"verbose", "version", "version-all", "version-intensity", "version-light",
+	"version-trace", "versiondb", "webxml",
+}
+
+// shortOptions are nmap's single letter options. A lone letter is never
+// read as a long option prefix.
+const shortOptions = "46ADFIMOPRSTVbdeghimnopqrsvw"
+
+// normalizeFlag returns the name an nmap argument is checked under, and the
+// value given with it by "=" or attached to -o and -i, like -oX/tmp/out.
+// Long options are expanded the way nmap's getopt_long_only reads them.
+func normalizeFlag(arg string) (name, value string, hasValue bool) {
+	body := strings.TrimPrefix(arg, "-")
+	double := strings.HasPrefix(body, "-")
+	body = strings.TrimPrefix(body, "-")
+	key, value, hasValue := strings.Cut(body, "=")
+
+	if double || len(key) > 1 || !strings.Contains(shortOptions, key) {
+		if long := expandLong(key); long != "" {
+			if len(long) == 2 {
+				return "-" + long, value, hasValue
+			}
+			return "--" + long, value, hasValue
+		}
+	}
+	if double || key == "" {
+		return "--" + key, value, hasValue
+	}
+	// -o and -i take the rest of the argument as their value, after the
+	// letter that selects the format or input.
+	if (key[0] == 'o' || key[0] == 'i') && len(key) > 2 {
+		attached := key[2:]
+		if hasValue {
+			attached += "=" + value
+		}
+		return "-" + key[:2], attached, true
+	}
+	return "-" + key, value, hasValue
+}
+
+// expandLong returns the long option named or uniquely abbreviated by key.
+func expandLong(key string) string {
+	var match string
+	for _, long := range longOptions {
+		if long == key {
+			return long
+		}
+		if key != "" && strings.HasPrefix(long, key) {
+			if match != "" {
+				return ""
+			}
+			match = long
+		}
+	}
+	return match
+}
+
+// SanitizeFlags checks nmap arguments before chainmap appends its output
+// and target arguments. Output options chainmap manages are removed and
+// returned in removed. Input options, stray targets, scripts loaded from
+// disk without policy.ScriptPaths and flags the policy does not allow are
+// errors. A nil policy allows every flag and script.
+func SanitizeFlags(args []string, policy *FlagPolicy) (clean, removed []string, err error) {
+	for i := 0; i < len(args); i++ {
+		start, arg := i, args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return nil, nil, fmt.Errorf("unexpected argument %q, targets go in -t, -l or stdin", arg)
+		}
+		name, value, inline := normalizeFlag(arg)
+		if !inline && valueFlags[name] {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("%s needs a value", name)
+			}
+			i++
+			value = args[i]
+			arg += " " + value
+		}
+
+		if reason, ok := rejectedFlags[name]; ok {
+			return nil, nil, fmt.Errorf("%s is not supported, %s", name, reason)
+		}
+		if outputFlags[name] {
+			removed = append(removed, arg)
+			continue
+		}
+		if err := policy.check(name, value); err != nil {
+			return nil, nil, err
+		}
+		clean = append(clean, args[start:i+1]...)
+	}
+	return clean, removed, nil
+}
+
+// CheckScripts applies the policy to scripts that chainmap passes to
+// --script itself, such as those of a script map.
+func (p *FlagPolicy) CheckScripts(scripts []string) error {
+	return p.check("--script", strings.Join(scripts, ","))
+}
+
+// check applies the policy to one flag and its value.
+func (p *FlagPolicy) check(name, value string) error {
+	if p == nil {
+		return nil
+	}
+	for _, pattern := range p.Deny {
+		if matchFlag(pattern, name) {
+			return fmt.Errorf("%s is denied by the flag policy", name)
+		}
+	}
+	if len(p.Allow) > 0 {
+		allowed := false
+		for _, pattern := range p.Allow {
+			allowed = allowed || matchFlag(pattern, name)
+		}
+		if !allowed {
+			return fmt.Errorf("%s is not allowed by the flag policy", name)
+		}
+	}
+	if p.ScriptPaths {
+		return nil
+	}
+	if pathFlags[name] {
+		return fmt.Errorf("%s reads files from disk, which the flag policy does not allow", name)
+	}
+	if name == "--script" {
+		for _, script := range strings.Split(value, ",") {
+			if scriptPath(script) {
+				return fmt.Errorf("--script %s loads a script from disk, which the flag policy does not allow", script)
+			}
+		}
+	}
+	return nil
+}
+
+// matchFlag matches a flag name against a policy pattern. Short options
+// with attached values, like -T4 or -PS22, need a pattern such as "-T*".
+func matchFlag(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// scriptPath reports whether an --script entry names a file or directory
+// rather than a script, category or expression.
+func scriptPath(script string) bool {
+	script = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(script), "+"))
+	return strings.ContainsAny(script, `/\`) ||
+		strings.HasPrefix(script, ".") || strings.HasPrefix(script, "~") ||
+		strings.HasSuffix(script, ".nse") || strings.HasSuffix(script, ".lua")
+}
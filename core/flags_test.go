@@ -0,0 +1,154 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFlags(t *testing.T) {
+	strict := &FlagPolicy{}
+	shared := &FlagPolicy{
+		Allow: []string{"-sT", "-sV", "-p", "-T*", "-Pn", "--script", "--top-ports"},
+		Deny:  []string{"-T5"},
+	}
+
+	tests := []struct {
+		name    string
+		flags   string
+		args    []string
+		policy  *FlagPolicy
+		clean   string
+		removed []string
+		wantErr bool
+	}{
+		{name: "Plain", flags: "-sS -sV -T4 -Pn", clean: "-sS -sV -T4 -Pn"},
+		{name: "Output removed", flags: "-sV -oN scan.txt -oX=out.xml --webxml -T4", clean: "-sV -T4", removed: []string{"-oN scan.txt", "-oX=out.xml", "--webxml"}},
+		{name: "Values kept", flags: "-p 22,80 --script vuln --exclude 10.0.0.1", clean: "-p 22,80 --script vuln --exclude 10.0.0.1"},
+		{name: "Input list", flags: "-sV -iL hosts.txt", wantErr: true},
+		{name: "Random targets", flags: "-iR 100", wantErr: true},
+		{name: "Resume", flags: "--resume scan.gnmap", wantErr: true},
+		{name: "Stray target", flags: "-sV 10.0.0.0/8", wantErr: true},
+		{name: "Missing value", flags: "-sV -p", wantErr: true},
+		{name: "Script path without policy", flags: "--script ./custom.nse", clean: "--script ./custom.nse"},
+		{name: "Script path", flags: "--script vuln,/tmp/evil.nse", policy: strict, wantErr: true},
+		{name: "Script file name", flags: "--script=evil.lua", policy: strict, wantErr: true},
+		{name: "Script expression", args: []string{"--script", "default and not intrusive"}, policy: strict, clean: "--script default and not intrusive"},
+		{name: "Data directory", flags: "--datadir /tmp/nmap", policy: strict, wantErr: true},
+		{name: "Script paths allowed", flags: "--script ./custom.nse", policy: &FlagPolicy{ScriptPaths: true}, clean: "--script ./custom.nse"},
+		{name: "Allowed", flags: "-sT -sV -T4 -p 443", policy: shared, clean: "-sT -sV -T4 -p 443"},
+		{name: "Not allowed", flags: "-sT -O", policy: shared, wantErr: true},
+		{name: "Denied", flags: "-sT -T5", policy: shared, wantErr: true},
+		{name: "Attached output", flags: "-sV -oX/tmp/x -oN/etc/cron.d/x", policy: strict, clean: "-sV", removed: []string{"-oX/tmp/x", "-oN/etc/cron.d/x"}},
+		{name: "Bare output letter", flags: "-sV -o Xfile", clean: "-sV", removed: []string{"-o Xfile"}},
+		{name: "Attached input list", flags: "-iL/etc/shadow", policy: strict, wantErr: true},
+		{name: "Single dash input list", flags: "-iL=/etc/shadow", wantErr: true},
+		{name: "Single dash script", flags: "-script=/tmp/evil.nse", policy: strict, wantErr: true},
+		{name: "Single dash script value", flags: "-script /tmp/evil.nse", policy: strict, wantErr: true},
+		{name: "Single dash data directory", flags: "-datadir=/tmp", policy: strict, wantErr: true},
+		{name: "Abbreviated data directory", flags: "--datad=/tmp", policy: strict, wantErr: true},
+		{name: "Abbreviated single dash", flags: "-servicedb /tmp/x", policy: strict, wantErr: true},
+		{name: "Abbreviated output", flags: "-sV --webx", clean: "-sV", removed: []string{"--webx"}},
+		{name: "Abbreviated resume", flags: "--resu scan.gnmap", wantErr: true},
+		{name: "Single dash denied", flags: "-sT -script vuln", policy: &FlagPolicy{Deny: []string{"--script"}}, wantErr: true},
+		{name: "Short options kept", flags: "-sS -sV -Pn -T4 -PS22 -n -vv", policy: strict, clean: "-sS -sV -Pn -T4 -PS22 -n -vv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if args == nil {
+				args = strings.Fields(tt.flags)
+			}
+			clean, removed, err := SanitizeFlags(args, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SanitizeFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if strings.Join(clean, " ") != tt.clean {
+				t.Errorf("clean = %q, want %q", strings.Join(clean, " "), tt.clean)
+			}
+			if !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("removed = %q, want %q", removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestCheckScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *FlagPolicy
+		scripts []string
+		wantErr bool
+	}{
+		{"No policy", nil, []string{"/tmp/x.nse"}, false},
+		{"Names", &FlagPolicy{}, []string{"http-title", "ssl-cert"}, false},
+		{"Path", &FlagPolicy{}, []string{"http-title", "/tmp/x.nse"}, true},
+		{"Script file", &FlagPolicy{}, []string{"evil.lua"}, true},
+		{"Paths allowed", &FlagPolicy{ScriptPaths: true}, []string{"/tmp/x.nse"}, false},
+		{"Script denied", &FlagPolicy{Deny: []string{"--script"}}, []string{"http-title"}, true},
+		{"Script not allowed", &FlagPolicy{Allow: []string{"-sV"}}, []string{"http-title"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.CheckScripts(tt.scripts); (err != nil) != tt.wantErr {
+				t.Errorf("CheckScripts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFlagPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	os.WriteFile(path, []byte("allow: [-sT, -sV, \"-T*\"]\ndeny: [--script]\nscript_paths: false\n"), 0644)
+
+	p, err := LoadFlagPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &FlagPolicy{Allow: []string{"-sT", "-sV", "-T*"}, Deny: []string{"--script"}}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("LoadFlagPolicy() = %+v, want %+v", p, want)
+	}
+
+	os.WriteFile(path, []byte("allow: [sT]\n"), 0644)
+	if _, err := LoadFlagPolicy(path); err == nil {
+		t.Error("LoadFlagPolicy() accepted a pattern without a dash")
+	}
+}
+
+func TestNormalizeFlag(t *testing.T) {
+	tests := []struct {
+		arg, name, value string
+	}{
+		{"-sV", "-sV", ""},
+		{"-T4", "-T4", ""},
+		{"-p", "-p", ""},
+		{"-vv", "-vv", ""},
+		{"-oX", "-oX", ""},
+		{"-oX/tmp/x", "-oX", "/tmp/x"},
+		{"-oX=out.xml", "-oX", "out.xml"},
+		{"-iL/etc/shadow", "-iL", "/etc/shadow"},
+		{"--oN=x", "-oN", "x"},
+		{"-script=vuln", "--script", "vuln"},
+		{"--script-h=x=1", "--script-help", "x=1"},
+		{"--script-a=x=1", "--script-a", "x=1"},
+		{"--datad=/tmp", "--datadir", "/tmp"},
+		{"-top", "--top-ports", ""},
+		{"--de", "--de", ""},
+		{"--no-such-flag", "--no-such-flag", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			name, value, _ := normalizeFlag(tt.arg)
+			if name != tt.name || value != tt.value {
+				t.Errorf("normalizeFlag(%q) = %q, %q, want %q, %q", tt.arg, name, value, tt.name, tt.value)
+			}
+		})
+	}
+}
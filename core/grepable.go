@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+// WriteGrepable writes run in nmap's grepable (-oG) format, so that results
+// of chainmap's XML-only scans can still be searched with grep and awk.
+func WriteGrepable(run *nmap.NmapRun, output string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Nmap %s scan initiated %s as: %s\n", run.Version, run.StartStr, run.Args)
+
+	for _, host := range run.Hosts {
+		name := ""
+		if len(host.Hostnames) > 0 {
+			name = host.Hostnames[0].Name
+		}
+		address := ""
+		if len(host.Addresses) > 0 {
+			address = host.Addresses[0].Addr
+		}
+		prefix := fmt.Sprintf("Host: %s (%s)", address, name)
+		fmt.Fprintf(&b, "%s\tStatus: %s\n", prefix, capitalize(host.Status.State))
+		if len(host.Ports) == 0 {
+			continue
+		}
+
+		ports := make([]string, 0, len(host.Ports))
+		for _, p := range host.Ports {
+			service := p.Service.Name
+			if p.Service.Tunnel != "" && service != "" {
+				service = p.Service.Tunnel + "|" + service
+			}
+			version := strings.Join(strings.Fields(p.Service.Product+" "+p.Service.Version+" "+p.Service.ExtraInfo), " ")
+			ports = append(ports, fmt.Sprintf("%d/%s/%s//%s//%s/",
+				p.PortId, p.State.State, p.Protocol, grepField(service), grepField(version)))
+		}
+		b.WriteString(prefix + "\tPorts: " + strings.Join(ports, ", "))
+		for _, extra := range host.ExtraPorts {
+			fmt.Fprintf(&b, "\tIgnored State: %s (%d)", extra.State, extra.Count)
+		}
+		b.WriteString("\n")
+	}
+
+	stats := run.RunStats
+	fmt.Fprintf(&b, "# Nmap done at %s -- %d IP %s (%d %s up) scanned in %.2f seconds\n",
+		stats.Finished.TimeStr,
+		stats.Hosts.Total, plural(stats.Hosts.Total, "address", "addresses"),
+		stats.Hosts.Up, plural(stats.Hosts.Up, "host", "hosts"),
+		stats.Finished.Elapsed)
+
+	return os.WriteFile(output, []byte(b.String()), 0644)
+}
+
+// grepField escapes the separators of a grepable port entry the way nmap
+// does.
+func grepField(s string) string {
+	return strings.NewReplacer("/", "|", ",", " ").Replace(s)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+func TestWriteGrepable(t *testing.T) {
+	run := &nmap.NmapRun{
+		Version:  "7.94",
+		StartStr: "Mon Oct 19 12:00:00 2026",
+		Args:     "nmap -sV 10.0.0.1",
+		Hosts: []nmap.Host{{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.1", AddrType: "ipv4"}},
+			Hostnames: []nmap.Hostname{{Name: "web.example", Type: "user"}},
+			Ports: []nmap.Port{
+				{Protocol: "tcp", PortId: 22, State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh", Product: "OpenSSH", Version: "8.2p1", ExtraInfo: "Ubuntu/Linux"}},
+				{Protocol: "tcp", PortId: 443, State: nmap.State{State: "open"}, Service: nmap.Service{Name: "http", Tunnel: "ssl"}},
+			},
+			ExtraPorts: []nmap.ExtraPorts{{State: "closed", Count: 998}},
+		}},
+	}
+	run.RunStats.Finished.TimeStr = "Mon Oct 19 12:00:05 2026"
+	run.RunStats.Finished.Elapsed = 5
+	run.RunStats.Hosts.Up, run.RunStats.Hosts.Total = 1, 1
+
+	path := filepath.Join(t.TempDir(), "scan.gnmap")
+	if err := WriteGrepable(run, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# Nmap 7.94 scan initiated Mon Oct 19 12:00:00 2026 as: nmap -sV 10.0.0.1",
+		"Host: 10.0.0.1 (web.example)\tStatus: Up",
+		"Host: 10.0.0.1 (web.example)\tPorts: 22/open/tcp//ssh//OpenSSH 8.2p1 Ubuntu|Linux/, 443/open/tcp//ssl|http///\tIgnored State: closed (998)",
+		"# Nmap done at Mon Oct 19 12:00:05 2026 -- 1 IP address (1 host up) scanned in 5.00 seconds",
+		"",
+	}, "\n")
+	if string(data) != want {
+		t.Errorf("WriteGrepable() wrote\n%s\nwant\n%s", data, want)
+	}
+}
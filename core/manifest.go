@@ -0,0 +1,67 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// JobRecord describes how a single nmap job ran.
+type JobRecord struct {
+	ID            int       `json:"id"`
+	Host          string    `json:"host"`
+	Ports         []string  `json:"ports,omitempty"`
+	Command       []string  `json:"command"`
+	ScriptCommand []string  `json:"script_command,omitempty"`
+	Status        string    `json:"status"`
+	ExitCode      int       `json:"exit_code"`
+	Started       time.Time `json:"started"`
+	Duration      float64   `json:"duration_seconds"`
+	StdoutLog     string    `json:"stdout_log,omitempty"`
+	StderrLog     string    `json:"stderr_log,omitempty"`
+	StderrTail    []string  `json:"stderr_tail,omitempty"`
+	Timeout       float64   `json:"timeout_seconds,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Worker        string    `json:"worker,omitempty"`
+	// Downgraded lists the flags -auto-downgrade rewrote for this job.
+	Downgraded []FlagChange `json:"downgraded,omitempty"`
+	// Artifacts is the folder of the job in an -output-dir tree.
+	Artifacts string `json:"artifacts,omitempty"`
+}
+
+// Failed reports whether the job did not produce a usable result, including
+// jobs skipped when the scan budget ran out.
+func (j JobRecord) Failed() bool {
+	return j.Status == "failed" || j.Status == "timeout" || j.Status == "not_scanned"
+}
+
+// Manifest maps every job of a run to its command line and outcome. The
+// manifest of an -output-dir tree also records how chainmap was started and
+// the files it wrote, relative to the tree.
+type Manifest struct {
+	Started  time.Time   `json:"started"`
+	Finished time.Time   `json:"finished"`
+	Command  []string    `json:"command,omitempty"`
+	Files    []string    `json:"files,omitempty"`
+	Jobs     []JobRecord `json:"jobs"`
+}
+
+func WriteManifest(path string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
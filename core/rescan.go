@@ -0,0 +1,159 @@
+package core
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+// RescanSelectors lists the selectors accepted by RescanTargets. "up" and
+// "timeout" pick hosts, "open" and "unknown" pick ports, and "failed" picks
+// failed jobs from a chainmap manifest.
+var RescanSelectors = []string{"up", "timeout", "open", "unknown", "failed"}
+
+// ParseRescanSelectors validates a comma separated selector list.
+func ParseRescanSelectors(list string) ([]string, error) {
+	var selected []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !containsString(RescanSelectors, name) {
+			return nil, fmt.Errorf("unknown rescan selector %q (available: %s)", name, strings.Join(RescanSelectors, ","))
+		}
+		selected = append(selected, name)
+	}
+	return selected, nil
+}
+
+// RescanTargets turns a previous nmap XML file or chainmap manifest.json
+// into target lines in the same "host" or "host:port" form as the input
+// list. Host selectors must all match; when port selectors are given only
+// the TCP ports matching all of them are kept, otherwise hosts are rescanned
+// with the default port selection.
+func RescanTargets(path string, selectors []string) ([]string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return manifestTargets(path, selectors)
+	}
+	return xmlTargets(path, selectors)
+}
+
+// timeoutRun reads the host attributes go-nmap does not expose.
+type timeoutRun struct {
+	Hosts []struct {
+		TimedOut string `xml:"timedout,attr"`
+	} `xml:"host"`
+}
+
+func xmlTargets(path string, selectors []string) ([]string, error) {
+	if containsString(selectors, "failed") {
+		return nil, fmt.Errorf("the failed selector needs a chainmap manifest.json")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run nmap.NmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	var attrs timeoutRun
+	if err := xml.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	portSelected := containsString(selectors, "open") || containsString(selectors, "unknown")
+
+	var lines []string
+	for i, host := range run.Hosts {
+		if containsString(selectors, "up") && host.Status.State != "up" {
+			continue
+		}
+		if containsString(selectors, "timeout") && (i >= len(attrs.Hosts) || attrs.Hosts[i].TimedOut != "true") {
+			continue
+		}
+
+		target := rescanHost(host)
+		if target == "" {
+			continue
+		}
+		if !portSelected {
+			lines = append(lines, target)
+			continue
+		}
+
+		for _, port := range host.Ports {
+			if port.Protocol != "tcp" {
+				continue
+			}
+			if containsString(selectors, "open") && port.State.State != "open" {
+				continue
+			}
+			if containsString(selectors, "unknown") && !unknownService(port.Service) {
+				continue
+			}
+			lines = append(lines, target+":"+strconv.Itoa(port.PortId))
+		}
+	}
+	return lines, nil
+}
+
+// rescanHost prefers the name the host was originally scanned as over its
+// resolved address.
+func rescanHost(host nmap.Host) string {
+	for _, hn := range host.Hostnames {
+		if hn.Type == "user" {
+			return hn.Name
+		}
+	}
+	if len(host.Addresses) > 0 {
+		return host.Addresses[0].Addr
+	}
+	return ""
+}
+
+func unknownService(s nmap.Service) bool {
+	switch strings.TrimSuffix(s.Name, "?") {
+	case "", "unknown", "tcpwrapped":
+		return true
+	}
+	return false
+}
+
+func manifestTargets(path string, selectors []string) ([]string, error) {
+	for _, s := range selectors {
+		if s != "timeout" && s != "failed" {
+			return nil, fmt.Errorf("the %s selector needs an nmap XML file", s)
+		}
+	}
+
+	m, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, rec := range m.Jobs {
+		if containsString(selectors, "timeout") && rec.Status != "timeout" {
+			continue
+		}
+		if containsString(selectors, "failed") && !rec.Failed() {
+			continue
+		}
+		if len(rec.Ports) == 0 {
+			lines = append(lines, rec.Host)
+			continue
+		}
+		for _, port := range rec.Ports {
+			lines = append(lines, rec.Host+":"+port)
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const rescanTestXML = `<?xml version="1.0"?>
+<nmaprun>
+  <host>
+    <status state="up"/>
+    <address addr="10.0.0.1" addrtype="ipv4"/>
+    <hostnames><hostname name="web.example" type="user"/></hostnames>
+    <ports>
+      <port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port>
+      <port protocol="tcp" portid="8443"><state state="open"/><service name="tcpwrapped"/></port>
+      <port protocol="tcp" portid="9000"><state state="filtered"/><service name="unknown"/></port>
+      <port protocol="udp" portid="161"><state state="open"/><service name="unknown"/></port>
+    </ports>
+  </host>
+  <host timedout="true">
+    <status state="up"/>
+    <address addr="10.0.0.2" addrtype="ipv4"/>
+  </host>
+  <host>
+    <status state="down"/>
+    <address addr="10.0.0.3" addrtype="ipv4"/>
+  </host>
+</nmaprun>`
+
+func TestRescanTargets(t *testing.T) {
+	dir := t.TempDir()
+	xmlPath := filepath.Join(dir, "results.xml")
+	if err := os.WriteFile(xmlPath, []byte(rescanTestXML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(dir, "manifest.json")
+	err := WriteManifest(manifestPath, &Manifest{Jobs: []JobRecord{
+		{Host: "10.0.0.4", Ports: []string{"80", "443"}, Status: "timeout"},
+		{Host: "10.0.0.5", Status: "failed"},
+		{Host: "10.0.0.6", Status: "ok"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		selectors []string
+		expected  []string
+	}{
+		{"All hosts", xmlPath, nil, []string{"web.example", "10.0.0.2", "10.0.0.3"}},
+		{"Up hosts", xmlPath, []string{"up"}, []string{"web.example", "10.0.0.2"}},
+		{"Open ports", xmlPath, []string{"open"}, []string{"web.example:22", "web.example:8443"}},
+		{"Unknown services", xmlPath, []string{"unknown"}, []string{"web.example:8443", "web.example:9000"}},
+		{"Open unknown services", xmlPath, []string{"open", "unknown"}, []string{"web.example:8443"}},
+		{"Timed out hosts", xmlPath, []string{"timeout"}, []string{"10.0.0.2"}},
+		{"Manifest timeouts", manifestPath, []string{"timeout"}, []string{"10.0.0.4:80", "10.0.0.4:443"}},
+		{"Manifest failures", manifestPath, []string{"failed"}, []string{"10.0.0.4:80", "10.0.0.4:443", "10.0.0.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RescanTargets(tt.path, tt.selectors)
+			if err != nil {
+				t.Fatalf("RescanTargets() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("RescanTargets() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lair-framework/go-nmap"
+	"gopkg.in/yaml.v3"
+)
+
+// ScriptMap maps nmap service names to the NSE scripts to run against
+// them. A key matches a service whose name equals it or starts with it, so
+// "http" also covers "https" and "http-proxy". The key "ssl" additionally
+// matches any SSL-tunnelled service, and "*" matches every open port.
+type ScriptMap map[string][]string
+
+// DefaultScriptMap is used for -auto-scripts when no -script-map is given.
+var DefaultScriptMap = ScriptMap{
+	"http":          {"http-title", "http-headers", "http-methods"},
+	"ssl":           {"ssl-enum-ciphers", "ssl-cert"},
+	"microsoft-ds":  {"smb-security-mode", "smb-os-discovery"},
+	"netbios-ssn":   {"smb-security-mode", "smb-os-discovery"},
+	"ssh":           {"ssh2-enum-algos"},
+	"ftp":           {"ftp-anon", "ftp-syst"},
+	"smtp":          {"smtp-commands"},
+	"mysql":         {"mysql-info"},
+	"ms-sql-s":      {"ms-sql-info", "ms-sql-ntlm-info"},
+	"ms-wbt-server": {"rdp-enum-encryption", "rdp-ntlm-info"},
+	"rdp":           {"rdp-enum-encryption", "rdp-ntlm-info"},
+	"snmp":          {"snmp-info"},
+	"domain":        {"dns-nsid"},
+}
+
+// LoadScriptMap reads a YAML file of service names to script lists.
+func LoadScriptMap(path string) (ScriptMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m ScriptMap
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ScriptsFor returns the sorted scripts mapped to service s.
+func (m ScriptMap) ScriptsFor(s nmap.Service) []string {
+	name := strings.ToLower(strings.TrimSuffix(s.Name, "?"))
+	seen := make(map[string]bool)
+	var scripts []string
+	for key, list := range m {
+		key = strings.ToLower(key)
+		switch {
+		case key == "*",
+			name != "" && strings.HasPrefix(name, key),
+			key == "ssl" && s.Tunnel == "ssl":
+			for _, script := range list {
+				if !seen[script] {
+					seen[script] = true
+					scripts = append(scripts, script)
+				}
+			}
+		}
+	}
+	sort.Strings(scripts)
+	return scripts
+}
+
+// SelectScripts returns the open TCP and UDP ports of host that have
+// scripts mapped to their service, in nmap's "T:22,U:161" form, and the
+// sorted union of those scripts.
+func (m ScriptMap) SelectScripts(host nmap.Host) (ports []string, scripts []string) {
+	seen := make(map[string]bool)
+	for _, port := range host.Ports {
+		if port.State.State != "open" {
+			continue
+		}
+		var proto string
+		switch port.Protocol {
+		case "tcp", "":
+			proto = "T:"
+		case "udp":
+			proto = "U:"
+		default:
+			continue
+		}
+		selected := m.ScriptsFor(port.Service)
+		if len(selected) == 0 {
+			continue
+		}
+		ports = append(ports, proto+strconv.Itoa(port.PortId))
+		for _, s := range selected {
+			if !seen[s] {
+				seen[s] = true
+				scripts = append(scripts, s)
+			}
+		}
+	}
+	sort.Strings(scripts)
+	return ports, scripts
+}
+
+// MergeScriptResults copies the script output of a targeted script scan
+// into the matching hosts and ports of base.
+func MergeScriptResults(base, scripts *nmap.NmapRun) {
+	for _, sh := range scripts.Hosts {
+		bh := findHost(base, sh)
+		if bh == nil {
+			continue
+		}
+		bh.HostScripts = appendScripts(bh.HostScripts, sh.HostScripts)
+		for _, sp := range sh.Ports {
+			for i := range bh.Ports {
+				bp := &bh.Ports[i]
+				if bp.PortId == sp.PortId && bp.Protocol == sp.Protocol {
+					bp.Scripts = appendScripts(bp.Scripts, sp.Scripts)
+				}
+			}
+		}
+	}
+}
+
+func findHost(run *nmap.NmapRun, host nmap.Host) *nmap.Host {
+	if len(host.Addresses) == 0 {
+		return nil
+	}
+	for i := range run.Hosts {
+		for _, a := range run.Hosts[i].Addresses {
+			if a.Addr == host.Addresses[0].Addr {
+				return &run.Hosts[i]
+			}
+		}
+	}
+	return nil
+}
+
+// appendScripts adds the scripts from extra whose IDs are not in list yet.
+func appendScripts(list, extra []nmap.Script) []nmap.Script {
+	for _, s := range extra {
+		dup := false
+		for _, existing := range list {
+			if existing.Id == s.Id {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			list = append(list, s)
+		}
+	}
+	return list
+}
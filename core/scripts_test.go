@@ -0,0 +1,88 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+func TestSelectScripts(t *testing.T) {
+	m := ScriptMap{
+		"http": {"http-title", "http-headers"},
+		"ssl":  {"ssl-cert"},
+		"ssh":  {"ssh2-enum-algos"},
+		"snmp": {"snmp-info"},
+	}
+
+	tests := []struct {
+		name            string
+		ports           []nmap.Port
+		expectedPorts   []string
+		expectedScripts []string
+	}{
+		{
+			name: "Mapped services",
+			ports: []nmap.Port{
+				{PortId: 22, State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh"}},
+				{PortId: 80, State: nmap.State{State: "open"}, Service: nmap.Service{Name: "http"}},
+			},
+			expectedPorts:   []string{"T:22", "T:80"},
+			expectedScripts: []string{"http-headers", "http-title", "ssh2-enum-algos"},
+		},
+		{
+			name: "SSL tunnel and prefix match",
+			ports: []nmap.Port{
+				{PortId: 8443, State: nmap.State{State: "open"}, Service: nmap.Service{Name: "https-alt", Tunnel: "ssl"}},
+			},
+			expectedPorts:   []string{"T:8443"},
+			expectedScripts: []string{"http-headers", "http-title", "ssl-cert"},
+		},
+		{
+			name: "UDP and SCTP",
+			ports: []nmap.Port{
+				{PortId: 22, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh"}},
+				{PortId: 161, Protocol: "udp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "snmp"}},
+				{PortId: 80, Protocol: "sctp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "http"}},
+			},
+			expectedPorts:   []string{"T:22", "U:161"},
+			expectedScripts: []string{"snmp-info", "ssh2-enum-algos"},
+		},
+		{
+			name: "Closed and unmapped ports",
+			ports: []nmap.Port{
+				{PortId: 80, State: nmap.State{State: "closed"}, Service: nmap.Service{Name: "http"}},
+				{PortId: 3306, State: nmap.State{State: "open"}, Service: nmap.Service{Name: "mysql"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports, scripts := m.SelectScripts(nmap.Host{Ports: tt.ports})
+			if !reflect.DeepEqual(ports, tt.expectedPorts) {
+				t.Errorf("ports = %v, want %v", ports, tt.expectedPorts)
+			}
+			if !reflect.DeepEqual(scripts, tt.expectedScripts) {
+				t.Errorf("scripts = %v, want %v", scripts, tt.expectedScripts)
+			}
+		})
+	}
+}
+
+func TestScriptsFor(t *testing.T) {
+	m := ScriptMap{
+		"*":     {"banner"},
+		"http":  {"http-title", "http-headers"},
+		"https": {"http-title", "ssl-cert"},
+		"ssl":   {"ssl-enum-ciphers", "ssl-cert"},
+	}
+	want := []string{"banner", "http-headers", "http-title", "ssl-cert", "ssl-enum-ciphers"}
+	// Map order changes between runs; the result must not.
+	for i := 0; i < 20; i++ {
+		got := m.ScriptsFor(nmap.Service{Name: "https", Tunnel: "ssl"})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ScriptsFor() = %v, want %v", got, want)
+		}
+	}
+}
@@ -194,6 +194,8 @@ const DefaultXSLT = `<?xml version="1.0" encoding="UTF-8"?>
             </div>
           </div>
 
+          <xsl:comment>chainmap:vulnerabilities</xsl:comment>
+
           <!-- Hosts List -->
           <xsl:for-each select="/NmapRun/host">
             <div class="host-card">
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+// ParseTimeout parses a timeout given as a Go duration such as "90s" or
+// "2h", or as a plain number of minutes like -timeout.
+func ParseTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if minutes, err := strconv.Atoi(s); err == nil {
+		if minutes <= 0 {
+			return 0, fmt.Errorf("timeout must be positive: %q", s)
+		}
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q", s)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("timeout must be positive: %q", s)
+	}
+	return d, nil
+}
+
+// SplitTimeouts strips "timeout=<value>" tokens from target lines, as in
+// "10.0.0.5:1-65535 timeout=2h", and returns the cleaned lines together with
+// the timeout of every host that had one. A host given several timeouts
+// keeps the longest. Other tokens after the target are ignored with a
+// warning.
+func SplitTimeouts(lines []string) ([]string, map[string]time.Duration, error) {
+	cleaned := make([]string, 0, len(lines))
+	timeouts := make(map[string]time.Duration)
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		target := fields[0]
+		for _, f := range fields[1:] {
+			value, ok := strings.CutPrefix(f, "timeout=")
+			if !ok {
+				logger.Warn("Ignoring %q after target %s", f, target)
+				continue
+			}
+			d, err := ParseTimeout(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", target, err)
+			}
+			host := targetHost(target)
+			if d > timeouts[host] {
+				timeouts[host] = d
+			}
+		}
+		cleaned = append(cleaned, target)
+	}
+	return cleaned, timeouts, nil
+}
+
+// LoadTimeouts reads a sidecar file of per-host timeouts. Each line holds a
+// host and its timeout, e.g. "10.0.0.5 2h" or "10.0.0.5 timeout=2h"; blank
+// lines and lines starting with # are ignored.
+func LoadTimeouts(path string) (map[string]time.Duration, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	timeouts := make(map[string]time.Duration)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"<host> <timeout>\"", path, n)
+		}
+		d, err := ParseTimeout(strings.TrimPrefix(fields[1], "timeout="))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		timeouts[targetHost(fields[0])] = d
+	}
+	return timeouts, scanner.Err()
+}
+
+// targetHost returns the host part of a target line, matching ParseTargets.
+func targetHost(target string) string {
+	host, _, _ := strings.Cut(target, ":")
+	return host
+}
+
+// CountPorts returns how many ports the nmap port specifications cover,
+// e.g. "80", "1-1024", "T:22,U:53" or "-" for all ports. Named services
+// count as one port each.
+func CountPorts(specs []string) int {
+	count := 0
+	for _, spec := range specs {
+		for _, part := range strings.Split(spec, ",") {
+			part = strings.TrimSpace(part)
+			if i := strings.Index(part, ":"); i >= 0 {
+				part = part[i+1:]
+			}
+			if part == "" {
+				continue
+			}
+			if part == "-" {
+				count += 65535
+				continue
+			}
+			lo, hi, isRange := strings.Cut(part, "-")
+			if !isRange {
+				count++
+				continue
+			}
+			start, end := 1, 65535
+			if n, err := strconv.Atoi(lo); err == nil {
+				start = n
+			}
+			if n, err := strconv.Atoi(hi); err == nil {
+				end = n
+			}
+			if end >= start {
+				count += end - start + 1
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		cleaned  []string
+		timeouts map[string]time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "No overrides",
+			lines:    []string{"10.0.0.1", "10.0.0.2:80"},
+			cleaned:  []string{"10.0.0.1", "10.0.0.2:80"},
+			timeouts: map[string]time.Duration{},
+		},
+		{
+			name:     "Duration and minutes",
+			lines:    []string{"10.0.0.1:1-65535 timeout=2h", "10.0.0.2 timeout=5"},
+			cleaned:  []string{"10.0.0.1:1-65535", "10.0.0.2"},
+			timeouts: map[string]time.Duration{"10.0.0.1": 2 * time.Hour, "10.0.0.2": 5 * time.Minute},
+		},
+		{
+			name:     "Longest wins",
+			lines:    []string{"10.0.0.1:22 timeout=1m", "10.0.0.1:80 timeout=90s"},
+			cleaned:  []string{"10.0.0.1:22", "10.0.0.1:80"},
+			timeouts: map[string]time.Duration{"10.0.0.1": 90 * time.Second},
+		},
+		{name: "Invalid value", lines: []string{"10.0.0.1 timeout=soon"}, wantErr: true},
+		{name: "Zero", lines: []string{"10.0.0.1 timeout=0"}, wantErr: true},
+		{
+			name:     "Unknown tokens",
+			lines:    []string{"10.0.0.1:80 retries=3 timeout=1h # web", "10.0.0.2 extra"},
+			cleaned:  []string{"10.0.0.1:80", "10.0.0.2"},
+			timeouts: map[string]time.Duration{"10.0.0.1": time.Hour},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaned, timeouts, err := SplitTimeouts(tt.lines)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(cleaned, tt.cleaned) {
+				t.Errorf("cleaned = %v, want %v", cleaned, tt.cleaned)
+			}
+			if !reflect.DeepEqual(timeouts, tt.timeouts) {
+				t.Errorf("timeouts = %v, want %v", timeouts, tt.timeouts)
+			}
+		})
+	}
+}
+
+func TestLoadTimeouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeouts.txt")
+	os.WriteFile(path, []byte("# slow hosts\n10.0.0.1 2h\n\n10.0.0.2:443 timeout=30\n"), 0644)
+
+	got, err := LoadTimeouts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"10.0.0.1": 2 * time.Hour, "10.0.0.2": 30 * time.Minute}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadTimeouts() = %v, want %v", got, want)
+	}
+
+	os.WriteFile(path, []byte("10.0.0.1\n"), 0644)
+	if _, err := LoadTimeouts(path); err == nil {
+		t.Error("LoadTimeouts() accepted a line without timeout")
+	}
+}
+
+func TestCountPorts(t *testing.T) {
+	tests := []struct {
+		specs []string
+		want  int
+	}{
+		{nil, 0},
+		{[]string{"80"}, 1},
+		{[]string{"22", "80", "443"}, 3},
+		{[]string{"1-1024"}, 1024},
+		{[]string{"T:22,80,U:53"}, 3},
+		{[]string{"-"}, 65535},
+		{[]string{"1-"}, 65535},
+		{[]string{"-1024"}, 1024},
+		{[]string{"http,ssh"}, 2},
+	}
+	for _, tt := range tests {
+		if got := CountPorts(tt.specs); got != tt.want {
+			t.Errorf("CountPorts(%v) = %d, want %d", tt.specs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+// WebURLs returns a sorted URL for every open web service in run, ready for
+// screenshot tools. Hosts keep the name they were scanned as, which matters
+// for virtual hosts; default ports are left out.
+func WebURLs(run *nmap.NmapRun) []string {
+	seen := make(map[string]bool)
+	var urls []string
+	for _, host := range run.Hosts {
+		name := rescanHost(host)
+		if name == "" {
+			continue
+		}
+		for _, port := range host.Ports {
+			if port.State.State != "open" || port.Protocol != "tcp" {
+				continue
+			}
+			scheme := webScheme(port.Service)
+			if scheme == "" {
+				continue
+			}
+			hostPort := net.JoinHostPort(name, strconv.Itoa(port.PortId))
+			if (scheme == "http" && port.PortId == 80) || (scheme == "https" && port.PortId == 443) {
+				hostPort = name
+				if strings.Contains(name, ":") {
+					hostPort = "[" + name + "]"
+				}
+			}
+			url := scheme + "://" + hostPort
+			if !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// webScheme returns the URL scheme of a web service, or "" for anything
+// else.
+func webScheme(s nmap.Service) string {
+	name := strings.ToLower(strings.TrimSuffix(s.Name, "?"))
+	switch {
+	case strings.HasPrefix(name, "https"), strings.HasPrefix(name, "ssl/http"):
+		return "https"
+	case strings.HasPrefix(name, "http"):
+		if s.Tunnel == "ssl" {
+			return "https"
+		}
+		return "http"
+	}
+	return ""
+}
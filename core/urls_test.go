@@ -0,0 +1,41 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+func TestWebURLs(t *testing.T) {
+	port := func(id int, state, name, tunnel string) nmap.Port {
+		return nmap.Port{Protocol: "tcp", PortId: id, State: nmap.State{State: state}, Service: nmap.Service{Name: name, Tunnel: tunnel}}
+	}
+	run := &nmap.NmapRun{Hosts: []nmap.Host{
+		{
+			Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+			Hostnames: []nmap.Hostname{{Name: "web.example", Type: "user"}},
+			Ports: []nmap.Port{
+				port(80, "open", "http", ""),
+				port(443, "open", "http", "ssl"),
+				port(8443, "open", "https-alt", ""),
+				port(22, "open", "ssh", ""),
+				port(8080, "filtered", "http-proxy", ""),
+			},
+		},
+		{
+			Addresses: []nmap.Address{{Addr: "fe80::1"}},
+			Ports:     []nmap.Port{port(80, "open", "http", ""), port(8080, "open", "http-proxy", "")},
+		},
+	}}
+	want := []string{
+		"http://[fe80::1]",
+		"http://[fe80::1]:8080",
+		"http://web.example",
+		"https://web.example",
+		"https://web.example:8443",
+	}
+	if got := WebURLs(run); !reflect.DeepEqual(got, want) {
+		t.Errorf("WebURLs() = %q, want %q", got, want)
+	}
+}
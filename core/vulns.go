@@ -0,0 +1,203 @@
+package core
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+// Vulnerability is a CVE reported by an NSE script for a host and port.
+type Vulnerability struct {
+	ID       string  `json:"id"`
+	CVSS     float64 `json:"cvss"`
+	Severity string  `json:"severity"`
+	Exploit  bool    `json:"exploit"`
+	URL      string  `json:"url,omitempty"`
+	Source   string  `json:"source"`
+	Host     string  `json:"host"`
+	Port     int     `json:"port"`
+	Protocol string  `json:"protocol"`
+	Service  string  `json:"service,omitempty"`
+	Product  string  `json:"product,omitempty"`
+	Version  string  `json:"version,omitempty"`
+}
+
+var cvePattern = regexp.MustCompile(`CVE-\d{4}-\d{4,}`)
+
+// Severity maps a CVSS score to its qualitative rating.
+func Severity(cvss float64) string {
+	switch {
+	case cvss >= 9.0:
+		return "critical"
+	case cvss >= 7.0:
+		return "high"
+	case cvss >= 4.0:
+		return "medium"
+	case cvss > 0:
+		return "low"
+	default:
+		return "unknown"
+	}
+}
+
+// ExtractVulnerabilities collects CVEs from vulners and vulscan output across
+// all hosts. Results are deduplicated per host, port and CVE and sorted by
+// CVSS score, highest first.
+func ExtractVulnerabilities(run *nmap.NmapRun) []Vulnerability {
+	seen := make(map[string]int)
+	var vulns []Vulnerability
+
+	for _, host := range run.Hosts {
+		ip := ""
+		if len(host.Addresses) > 0 {
+			ip = host.Addresses[0].Addr
+		}
+
+		for _, port := range host.Ports {
+			for _, script := range port.Scripts {
+				var found []Vulnerability
+				switch script.Id {
+				case "vulners":
+					found = ParseVulners(script)
+				case "vulscan":
+					found = ParseVulscan(script.Output)
+				default:
+					continue
+				}
+
+				for _, v := range found {
+					v.Source = script.Id
+					v.Host = ip
+					v.Port = port.PortId
+					v.Protocol = port.Protocol
+					v.Service = port.Service.Name
+					v.Product = port.Service.Product
+					v.Version = port.Service.Version
+
+					key := ip + "|" + strconv.Itoa(port.PortId) + "/" + port.Protocol + "|" + v.ID
+					if i, ok := seen[key]; ok {
+						if v.CVSS > vulns[i].CVSS {
+							vulns[i].CVSS = v.CVSS
+							vulns[i].Severity = v.Severity
+						}
+						vulns[i].Exploit = vulns[i].Exploit || v.Exploit
+						if vulns[i].URL == "" {
+							vulns[i].URL = v.URL
+						}
+						continue
+					}
+					seen[key] = len(vulns)
+					vulns = append(vulns, v)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(vulns, func(i, j int) bool {
+		if vulns[i].CVSS != vulns[j].CVSS {
+			return vulns[i].CVSS > vulns[j].CVSS
+		}
+		if vulns[i].Host != vulns[j].Host {
+			return vulns[i].Host < vulns[j].Host
+		}
+		if vulns[i].Port != vulns[j].Port {
+			return vulns[i].Port < vulns[j].Port
+		}
+		return vulns[i].ID < vulns[j].ID
+	})
+	return vulns
+}
+
+// ParseVulners reads CVEs from the vulners script, preferring its structured
+// tables and falling back to the text output.
+func ParseVulners(script nmap.Script) []Vulnerability {
+	var vulns []Vulnerability
+	var walk func(tables []nmap.Table)
+	walk = func(tables []nmap.Table) {
+		for _, t := range tables {
+			if v, ok := vulnersTableEntry(t); ok {
+				vulns = append(vulns, v)
+			}
+			walk(t.Table)
+		}
+	}
+	walk(script.Tables)
+
+	if len(vulns) > 0 {
+		return vulns
+	}
+	return ParseVulnersOutput(script.Output)
+}
+
+func vulnersTableEntry(t nmap.Table) (Vulnerability, bool) {
+	var v Vulnerability
+	for _, e := range t.Elements {
+		switch e.Key {
+		case "id":
+			v.ID = e.Value
+		case "cvss":
+			v.CVSS, _ = strconv.ParseFloat(e.Value, 64)
+		case "is_exploit":
+			v.Exploit = e.Value == "true"
+		}
+	}
+	if !cvePattern.MatchString(v.ID) {
+		return v, false
+	}
+	v.ID = cvePattern.FindString(v.ID)
+	v.URL = "https://vulners.com/cve/" + v.ID
+	v.Severity = Severity(v.CVSS)
+	return v, true
+}
+
+// ParseVulnersOutput parses the text form of vulners output, where each
+// finding is a tab separated "ID CVSS URL [*EXPLOIT*]" line.
+func ParseVulnersOutput(output string) []Vulnerability {
+	var vulns []Vulnerability
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !cvePattern.MatchString(fields[0]) {
+			continue
+		}
+		cvss, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		v := Vulnerability{
+			ID:       cvePattern.FindString(fields[0]),
+			CVSS:     cvss,
+			Severity: Severity(cvss),
+		}
+		for _, f := range fields[2:] {
+			if f == "*EXPLOIT*" {
+				v.Exploit = true
+			} else if strings.HasPrefix(f, "http") {
+				v.URL = f
+			}
+		}
+		vulns = append(vulns, v)
+	}
+	return vulns
+}
+
+// ParseVulscan extracts CVE IDs from vulscan output. vulscan does not report
+// scores, so severity is left unknown.
+func ParseVulscan(output string) []Vulnerability {
+	var vulns []Vulnerability
+	seen := make(map[string]bool)
+	for _, id := range cvePattern.FindAllString(output, -1) {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		vulns = append(vulns, Vulnerability{
+			ID:       id,
+			Severity: Severity(0),
+			URL:      "https://nvd.nist.gov/vuln/detail/" + id,
+		})
+	}
+	return vulns
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+const vulnersOutput = `
+  cpe:/a:apache:http_server:2.4.49: 
+    	CVE-2021-42013	9.8	https://vulners.com/cve/CVE-2021-42013	*EXPLOIT*
+    	PACKETSTORM:164941	9.8	https://vulners.com/packetstorm/PACKETSTORM:164941	*EXPLOIT*
+    	CVE-2021-41773	7.5	https://vulners.com/cve/CVE-2021-41773
+    	CVE-2021-34798	5.0	https://vulners.com/cve/CVE-2021-34798
+`
+
+func TestParseVulnersOutput(t *testing.T) {
+	vulns := ParseVulnersOutput(vulnersOutput)
+	if len(vulns) != 3 {
+		t.Fatalf("ParseVulnersOutput() returned %d findings, want 3", len(vulns))
+	}
+	if vulns[0].ID != "CVE-2021-42013" || vulns[0].CVSS != 9.8 || !vulns[0].Exploit || vulns[0].Severity != "critical" {
+		t.Errorf("ParseVulnersOutput()[0] = %+v", vulns[0])
+	}
+	if vulns[1].Exploit {
+		t.Errorf("ParseVulnersOutput()[1] should not be flagged as exploit")
+	}
+}
+
+func TestExtractVulnerabilities(t *testing.T) {
+	port := func(id int, scripts ...nmap.Script) nmap.Port {
+		return nmap.Port{PortId: id, Protocol: "tcp", State: nmap.State{State: "open"}, Scripts: scripts}
+	}
+	run := &nmap.NmapRun{
+		Hosts: []nmap.Host{
+			{
+				Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+				Ports: []nmap.Port{
+					port(80,
+						nmap.Script{Id: "vulners", Output: vulnersOutput},
+						nmap.Script{Id: "vulscan", Output: "[CVE-2021-41773] path traversal\n[CVE-2020-0001] other"},
+					),
+				},
+			},
+			{
+				Addresses: []nmap.Address{{Addr: "10.0.0.2"}},
+				Ports: []nmap.Port{
+					port(443, nmap.Script{Id: "vulners", Tables: []nmap.Table{{
+						Key: "cpe:/a:openbsd:openssh:8.2p1",
+						Table: []nmap.Table{{Elements: []nmap.Element{
+							{Key: "id", Value: "CVE-2023-38408"},
+							{Key: "cvss", Value: "9.8"},
+							{Key: "is_exploit", Value: "true"},
+						}}},
+					}}}),
+				},
+			},
+		},
+	}
+
+	vulns := ExtractVulnerabilities(run)
+
+	want := []struct {
+		id   string
+		host string
+		cvss float64
+	}{
+		{"CVE-2021-42013", "10.0.0.1", 9.8},
+		{"CVE-2023-38408", "10.0.0.2", 9.8},
+		{"CVE-2021-41773", "10.0.0.1", 7.5},
+		{"CVE-2021-34798", "10.0.0.1", 5.0},
+		{"CVE-2020-0001", "10.0.0.1", 0},
+	}
+	if len(vulns) != len(want) {
+		t.Fatalf("ExtractVulnerabilities() returned %d findings, want %d: %+v", len(vulns), len(want), vulns)
+	}
+	for i, w := range want {
+		if vulns[i].ID != w.id || vulns[i].Host != w.host || vulns[i].CVSS != w.cvss {
+			t.Errorf("ExtractVulnerabilities()[%d] = %s %s %.1f, want %s %s %.1f", i, vulns[i].ID, vulns[i].Host, vulns[i].CVSS, w.id, w.host, w.cvss)
+		}
+	}
+}
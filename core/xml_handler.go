@@ -41,7 +41,9 @@ func ParseXML(path string) (*nmap.NmapRun, error) {
 	return &result, nil
 }
 
-func MergeXMLs(inputs []string, output string) error {
+// MergeRuns parses the nmap XML files in inputs into a single run. Files
+// that fail to parse are skipped with a warning.
+func MergeRuns(inputs []string) (*nmap.NmapRun, error) {
 	var merged *nmap.NmapRun
 	var totalElapsed float64
 
@@ -67,12 +69,16 @@ func MergeXMLs(inputs []string, output string) error {
 	}
 
 	if merged == nil {
-		return fmt.Errorf("no valid XML data to merge")
+		return nil, fmt.Errorf("no valid XML data to merge")
 	}
 
 	merged.RunStats.Finished.Elapsed = float32(totalElapsed)
+	return merged, nil
+}
 
-	data, err := xml.MarshalIndent(merged, "", "  ")
+// WriteXML writes run as nmap XML that references the nmap.xsl stylesheet.
+func WriteXML(run *nmap.NmapRun, output string) error {
+	data, err := xml.MarshalIndent(run, "", "  ")
 	if err != nil {
 		return err
 	}
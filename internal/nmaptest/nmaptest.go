@@ -0,0 +1,40 @@
+// Package nmaptest provides a fake nmap for tests that run scans.
+package nmaptest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Script is a fake nmap that writes a one-host XML result with port 22 open
+// to the -oX path, after sleeping $FAKE_SLEEP seconds when set.
+const Script = `#!/bin/sh
+[ -n "$FAKE_SLEEP" ] && sleep "$FAKE_SLEEP"
+out=""
+prev=""
+for a in "$@"; do
+  [ "$prev" = "-oX" ] && out="$a"
+  prev="$a"
+  host="$a"
+done
+cat > "$out" <<XML
+<?xml version="1.0"?>
+<nmaprun scanner="nmap" args="nmap" version="7.94">
+<host><status state="up"/><address addr="$host" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="22"><state state="open"/><service name="ssh" product="OpenSSH"/></port></ports>
+</host>
+<runstats><finished elapsed="1"/><hosts up="1" down="0" total="1"/></runstats>
+</nmaprun>
+XML
+`
+
+// Install puts Script first on PATH as nmap for the rest of the test.
+func Install(t testing.TB) {
+	t.Helper()
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "nmap"), []byte(Script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
@@ -1,9 +1,18 @@
 package logger
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
 var (
@@ -16,34 +25,233 @@ var (
 	Bold    = color.New(color.Bold).SprintFunc()
 )
 
-func Info(format string, args ...interface{}) {
-	prefix := Blue("[INFO]")
+// Level is the minimum severity a log line needs to be written.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config controls where and how log lines are written.
+type Config struct {
+	Level   Level
+	Format  string
+	LogFile string
+}
+
+var (
+	mu        sync.Mutex
+	minLevel            = LevelInfo
+	logFormat           = FormatText
+	output    io.Writer = os.Stderr
+	file      io.WriteCloser
+	created   bool
+	colors    = isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())
+)
+
+// Configure applies cfg to the package logger. Log lines always go to
+// stderr; with LogFile set they are also appended to that file.
+func Configure(cfg Config) error {
+	if cfg.Format == "" {
+		cfg.Format = FormatText
+	}
+	if cfg.Format != FormatText && cfg.Format != FormatJSON {
+		return fmt.Errorf("unknown log format %q (use text or json)", cfg.Format)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if cfg.LogFile != "" {
+		f, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0644)
+		isNew := err == nil
+		if errors.Is(err, fs.ErrExist) {
+			f, err = os.OpenFile(cfg.LogFile, os.O_WRONLY|os.O_APPEND, 0644)
+		}
+		if err != nil {
+			return err
+		}
+		if file != nil {
+			file.Close()
+		}
+		file = f
+		created = isNew
+	}
+	minLevel = cfg.Level
+	logFormat = cfg.Format
+	return nil
+}
+
+// CreatedLogFile reports whether Configure created the log file rather
+// than appending to an existing one.
+func CreatedLogFile() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return created
+}
+
+// SetOutput redirects console log lines to w.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+}
+
+// Close closes the log file, if any.
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+		file = nil
+	}
+}
+
+// Entry is a logger carrying structured fields such as host and job ID.
+type Entry struct {
+	fields []field
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// With returns an entry carrying the given key/value pairs.
+func With(keyvals ...interface{}) *Entry {
+	return (&Entry{}).With(keyvals...)
+}
+
+// With returns a copy of e with the given key/value pairs appended.
+func (e *Entry) With(keyvals ...interface{}) *Entry {
+	fields := append([]field{}, e.fields...)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields = append(fields, field{key: fmt.Sprint(keyvals[i]), value: keyvals[i+1]})
+	}
+	return &Entry{fields: fields}
+}
+
+func (e *Entry) Debug(format string, args ...interface{}) {
+	e.log(LevelDebug, "DEBUG", color.FgMagenta, format, args...)
+}
+
+func (e *Entry) Info(format string, args ...interface{}) {
+	e.log(LevelInfo, "INFO", color.FgBlue, format, args...)
+}
+
+func (e *Entry) Success(format string, args ...interface{}) {
+	e.log(LevelInfo, "SUCCESS", color.FgGreen, format, args...)
+}
+
+func (e *Entry) Warn(format string, args ...interface{}) {
+	e.log(LevelWarn, "WARN", color.FgYellow, format, args...)
+}
+
+func (e *Entry) Error(format string, args ...interface{}) {
+	e.log(LevelError, "ERROR", color.FgRed, format, args...)
+}
+
+func (e *Entry) log(lvl Level, tag string, paint color.Attribute, format string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if lvl < minLevel {
+		return
+	}
+
 	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s %s\n", prefix, msg)
+	now := time.Now()
+
+	if logFormat == FormatJSON {
+		line := e.jsonLine(now, lvl, tag, msg)
+		fmt.Fprintln(output, line)
+		if file != nil {
+			fmt.Fprintln(file, line)
+		}
+		return
+	}
+
+	prefix := "[" + tag + "]"
+	if colors {
+		c := color.New(paint)
+		c.EnableColor()
+		fmt.Fprintf(output, "%s %s%s\n", c.Sprint(prefix), msg, e.textFields())
+	} else {
+		fmt.Fprintf(output, "%s %s%s\n", prefix, msg, e.textFields())
+	}
+	if file != nil {
+		fmt.Fprintf(file, "%s %s %s%s\n", now.Format(time.RFC3339), prefix, msg, e.textFields())
+	}
+}
+
+func (e *Entry) textFields() string {
+	var sb strings.Builder
+	for _, f := range e.fields {
+		fmt.Fprintf(&sb, " %s=%v", f.key, f.value)
+	}
+	return sb.String()
+}
+
+func (e *Entry) jsonLine(now time.Time, lvl Level, tag, msg string) string {
+	record := map[string]interface{}{
+		"time":  now.Format(time.RFC3339Nano),
+		"level": lvl.String(),
+		"msg":   msg,
+	}
+	if tag == "SUCCESS" {
+		record["status"] = "success"
+	}
+	for _, f := range e.fields {
+		record[f.key] = f.value
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Sprintf(`{"level":"error","msg":%q}`, err.Error())
+	}
+	return string(data)
+}
+
+var std = &Entry{}
+
+func Info(format string, args ...interface{}) {
+	std.Info(format, args...)
 }
 
 func Success(format string, args ...interface{}) {
-	prefix := Green("[SUCCESS]")
-	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s %s\n", prefix, msg)
+	std.Success(format, args...)
 }
 
 func Warn(format string, args ...interface{}) {
-	prefix := Yellow("[WARN]")
-	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s %s\n", prefix, msg)
+	std.Warn(format, args...)
 }
 
 func Error(format string, args ...interface{}) {
-	prefix := Red("[ERROR]")
-	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s %s\n", prefix, msg)
+	std.Error(format, args...)
 }
 
 func Debug(format string, args ...interface{}) {
-	prefix := Magenta("[DEBUG]")
-	msg := fmt.Sprintf(format, args...)
-	fmt.Printf("%s %s\n", prefix, msg)
+	std.Debug(format, args...)
 }
 
 func PrintBanner() {
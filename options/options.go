@@ -2,22 +2,63 @@ package options
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/ihsanlearn/chainmap/logger"
 	"github.com/projectdiscovery/goflags"
 )
 
 type Options struct {
-	InputList  string
-	Target     string
-	NmapFlags  string
-	Threads    int
-	Timeout    int
-	Silent     bool
-	Version    bool
-	OutputFile string
-	FastMode   bool
-	DeepMode   bool
+	// Targets are extra target lines set by API callers rather than flags.
+	// When present, stdin is not read.
+	Targets []string
+
+	InputList        string
+	Target           string
+	Rescan           string
+	RescanSelect     string
+	NmapFlags        string
+	Threads          int
+	MinThreads       int
+	Adaptive         bool
+	Timeout          int
+	TimeoutFile      string
+	ScaleTimeout     bool
+	Budget           time.Duration
+	Silent           bool
+	Verbose          bool
+	Debug            bool
+	LogFile          string
+	LogFormat        string
+	NoProgress       bool
+	StatsEvery       time.Duration
+	Version          bool
+	Outputs          goflags.StringSlice
+	OutputDir        string
+	StateDir         string
+	Columns          string
+	Filter           string
+	Summary          string
+	SummaryJSON      string
+	MarkdownTemplate string
+	Database         string
+	FastMode         bool
+	DeepMode         bool
+	AutoDowngrade    bool
+	FlagPolicy       string
+	RunAs            string
+	Sandbox          bool
+	AutoScripts      bool
+	ScriptMap        string
+	DryRun           bool
+	PlanOutput       string
+	FailCVSS         float64
+	Coordinator      string
+	ClusterToken     string
+	QueueIn          string
+	QueueOut         string
+	MetricsAddr      string
 }
 
 const Version = "1.0.0"
@@ -32,32 +73,151 @@ func ParseOptions() *Options {
 	flagSet.CreateGroup("input", "Input",
 		flagSet.StringVarP(&opts.InputList, "list", "l", "", "Input file containing list of IPs "),
 		flagSet.StringVarP(&opts.Target, "target", "t", "", "Single target IP"),
+		flagSet.StringVarP(&opts.Rescan, "rescan", "", "", "Take targets from a previous nmap XML file or chainmap manifest.json"),
+		flagSet.StringVarP(&opts.RescanSelect, "rescan-select", "", "", "Rescan selectors (up,timeout,open,unknown,failed)"),
+		flagSet.StringVarP(&opts.QueueIn, "queue-in", "", "", "Consume targets from a queue (spool directory or redis://host/?key=list) until interrupted"),
+		flagSet.StringVarP(&opts.QueueOut, "queue-out", "", "", "Publish a JSON result per host to a queue (spool directory or redis:// URL)"),
 	)
 
 	flagSet.CreateGroup("config", "Configuration",
-		flagSet.IntVarP(&opts.Threads, "threads", "c", 5, "Number of concurrent threads"),
+		flagSet.IntVarP(&opts.Threads, "threads", "c", 5, "Number of concurrent threads (maximum in adaptive mode)"),
+		flagSet.BoolVarP(&opts.Adaptive, "adaptive", "", false, "Adapt concurrency to timeouts and failures"),
+		flagSet.IntVarP(&opts.MinThreads, "min-threads", "", 1, "Starting concurrency in adaptive mode"),
 		flagSet.IntVarP(&opts.Timeout, "timeout", "T", 10, "Timeout in minutes"),
+		flagSet.StringVarP(&opts.TimeoutFile, "timeouts", "", "", "File with per-host timeouts (\"<host> <timeout>\" per line)"),
+		flagSet.BoolVarP(&opts.ScaleTimeout, "scale-timeout", "", false, "Scale -timeout by each job's port count (-timeout is per 1000 ports)"),
+		flagSet.DurationVarP(&opts.Budget, "budget", "", 0, "Wall-clock budget for the whole run; jobs not started in time are reported as not scanned"),
 		flagSet.StringVarP(&opts.NmapFlags, "nmap-flags", "n", "", "Nmap flags to use"),
-		flagSet.StringVarP(&opts.OutputFile, "output", "o", "results.xml", "File to store merged XML results"),
+		flagSet.StringVarP(&opts.FlagPolicy, "flag-policy", "", "", "YAML allowlist/denylist for nmap flags, for shared scanner hosts"),
+		flagSet.StringVarP(&opts.RunAs, "run-as", "", "", "When started as root, drop to this user after setup, keeping only the capabilities nmap needs"),
+		flagSet.BoolVarP(&opts.Sandbox, "sandbox", "", false, "Run nmap in a Landlock sandbox that may only write to its output directory"),
+		flagSet.StringVarP(&opts.StateDir, "state-dir", "", "chainmap-state", "Directory for per-job nmap logs and the job manifest"),
 		flagSet.BoolVarP(&opts.FastMode, "fast", "", false, "Fast Scan Mode"),
 		flagSet.BoolVarP(&opts.DeepMode, "deep", "", false, "Deep Scan Mode"),
+		flagSet.BoolVarP(&opts.AutoDowngrade, "auto-downgrade", "", false, "Rewrite flags that need root (e.g. -sS to -sT, no -O) when nmap has no raw socket access"),
+		flagSet.BoolVarP(&opts.AutoScripts, "auto-scripts", "", false, "Run NSE scripts picked from detected services in a second phase"),
+		flagSet.StringVarP(&opts.ScriptMap, "script-map", "", "", "YAML file mapping services to NSE scripts for -auto-scripts"),
+		flagSet.BoolVarP(&opts.DryRun, "dry-run", "", false, "Print the nmap command for each job without running it"),
+		flagSet.StringVarP(&opts.PlanOutput, "plan-output", "", "", "Export the dry-run plan as a shell script (.sh) or JSON (.json)"),
+		flagSet.StringVarP(&opts.Coordinator, "coordinator", "", "", "Listen address for chainmap workers; jobs are scanned by workers instead of locally"),
+		flagSet.StringVarP(&opts.ClusterToken, "cluster-token", "", os.Getenv("CHAINMAP_CLUSTER_TOKEN"), "Bearer token workers must present (default $CHAINMAP_CLUSTER_TOKEN)"),
+		flagSet.StringVarP(&opts.MetricsAddr, "metrics-addr", "", "", "Serve Prometheus metrics on this address under /metrics"),
 	)
 
-	flagSet.CreateGroup("misc", "Optimization",
-		flagSet.BoolVarP(&opts.Silent, "silent", "s", false, "Silent mode"),
+	flagSet.CreateGroup("output", "Output", append(outputFlags(flagSet, opts),
+		flagSet.StringVarP(&opts.OutputDir, "output-dir", "", "", "Write merged results, per-host artifacts, a manifest and index.html into this directory"),
+	)...)
+
+	flagSet.CreateGroup("misc", "Optimization", append(logFlags(flagSet, opts),
+		flagSet.BoolVarP(&opts.NoProgress, "no-progress", "", false, "Disable the progress display"),
+		flagSet.DurationVarP(&opts.StatsEvery, "stats-interval", "", 10*time.Second, "Interval for nmap stats and non-TTY status lines"),
 		flagSet.BoolVarP(&opts.Version, "version", "V", false, "Display application version"),
-	)
+	)...)
 
 	if err := flagSet.Parse(); err != nil {
 		logger.Error("Failed parsing flags: %s", err)
 		os.Exit(1)
 	}
 
+	configureLogger(opts)
+
+	// nmap's --stats-every takes whole milliseconds at the finest.
+	if opts.StatsEvery < time.Millisecond {
+		logger.Error("-stats-interval must be at least 1ms")
+		os.Exit(1)
+	}
+
 	if opts.Version {
 		logger.Info("Chainmap v%s", Version)
 		os.Exit(0)
 	}
 
-
 	return opts
 }
+
+// ParseReportOptions parses the arguments following "chainmap merge" or
+// "chainmap report". The remaining arguments are the XML files and
+// directories to import.
+func ParseReportOptions(args []string) (*Options, []string) {
+	opts := &Options{}
+
+	flagSet := goflags.NewFlagSet()
+
+	flagSet.SetDescription("Merge existing nmap XML files into chainmap reports without scanning.\n\nUsage: chainmap merge [flags] <file.xml|dir>...")
+
+	flagSet.CreateGroup("output", "Output", outputFlags(flagSet, opts)...)
+
+	flagSet.CreateGroup("misc", "Optimization", logFlags(flagSet, opts)...)
+
+	if err := flagSet.Parse(subcommandArgs(args)...); err != nil {
+		logger.Error("Failed parsing flags: %s", err)
+		os.Exit(1)
+	}
+
+	configureLogger(opts)
+
+	return opts, flagSet.CommandLine.Args()
+}
+
+// subcommandArgs keeps goflags from falling back to os.Args, which still
+// holds the subcommand name, when no arguments were given.
+func subcommandArgs(args []string) []string {
+	if len(args) == 0 {
+		return []string{"--"}
+	}
+	return args
+}
+
+// outputFlags registers the flags that control merging and reporting,
+// shared by scans and the merge subcommand.
+func outputFlags(flagSet *goflags.FlagSet, opts *Options) []*goflags.FlagData {
+	return []*goflags.FlagData{
+		flagSet.StringSliceVarP(&opts.Outputs, "output", "o", []string{"results.xml", "results.html"}, "Write merged results to this file, repeatable ([format:]path[?option=value], formats: xml,html,json,sarif,csv,xlsx,md)", goflags.StringSliceOptions),
+		flagSet.StringVarP(&opts.Filter, "filter", "f", "", "Only report results matching this filter (e.g. \"port in (22,3389) and state == open\")"),
+		flagSet.StringVarP(&opts.Columns, "columns", "", "", "Default columns and order for csv/xlsx outputs"),
+		flagSet.StringVarP(&opts.Summary, "summary", "", "", "Summary views (ports,services,port-hosts,products,no-open,failed,vulns, all, none)"),
+		flagSet.StringVarP(&opts.SummaryJSON, "summary-json", "", "", "Write the summary views as JSON to this file (- for stdout)"),
+		flagSet.StringVarP(&opts.MarkdownTemplate, "md-template", "", "", "Default Go text/template file for md outputs"),
+		flagSet.StringVarP(&opts.Database, "db", "", "", "Append results to this SQLite database"),
+		flagSet.VarP((*floatVar)(&opts.FailCVSS), "fail-cvss", "", "Exit with an error when a vulnerability reaches this CVSS score"),
+	}
+}
+
+func logFlags(flagSet *goflags.FlagSet, opts *Options) []*goflags.FlagData {
+	return []*goflags.FlagData{
+		flagSet.BoolVarP(&opts.Silent, "silent", "s", false, "Silent mode (errors only)"),
+		flagSet.BoolVarP(&opts.Verbose, "verbose", "v", false, "Show debug logs"),
+		flagSet.BoolVarP(&opts.Debug, "debug", "", false, "Show debug logs (same as -verbose)"),
+		flagSet.StringVarP(&opts.LogFile, "log-file", "", "", "Also append logs to this file"),
+		flagSet.StringVarP(&opts.LogFormat, "log-format", "", "text", "Log format (text, json)"),
+	}
+}
+
+func configureLogger(opts *Options) {
+	logLevel := logger.LevelInfo
+	if opts.Silent {
+		logLevel = logger.LevelError
+	} else if opts.Verbose || opts.Debug {
+		logLevel = logger.LevelDebug
+	}
+	if err := logger.Configure(logger.Config{Level: logLevel, Format: opts.LogFormat, LogFile: opts.LogFile}); err != nil {
+		logger.Error("Failed configuring logger: %s", err)
+		os.Exit(1)
+	}
+}
+
+// floatVar adapts a float64 option to flag.Value.
+type floatVar float64
+
+func (f *floatVar) String() string {
+	return strconv.FormatFloat(float64(*f), 'f', -1, 64)
+}
+
+func (f *floatVar) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*f = floatVar(v)
+	return nil
+}
@@ -0,0 +1,52 @@
+package options
+
+import (
+	"os"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/projectdiscovery/goflags"
+)
+
+// QueryOptions are the flags of the query subcommand.
+type QueryOptions struct {
+	Database string
+	Scans    bool
+	Port     int
+	Service  string
+	Product  string
+	Below    string
+	SQL      string
+	Latest   bool
+	JSON     bool
+}
+
+// ParseQueryOptions parses the arguments following "chainmap query".
+func ParseQueryOptions(args []string) *QueryOptions {
+	opts := &QueryOptions{}
+
+	flagSet := goflags.NewFlagSet()
+
+	flagSet.SetDescription("Query a chainmap results database")
+
+	flagSet.CreateGroup("query", "Query",
+		flagSet.StringVarP(&opts.Database, "db", "", "chainmap.db", "SQLite results database"),
+		flagSet.BoolVarP(&opts.Scans, "scans", "", false, "List stored scans"),
+		flagSet.IntVarP(&opts.Port, "open-port", "p", 0, "Hosts with this port open"),
+		flagSet.StringVarP(&opts.Service, "service", "", "", "Open ports whose service name matches"),
+		flagSet.StringVarP(&opts.Product, "product", "", "", "Open ports whose product matches"),
+		flagSet.StringVarP(&opts.Below, "below", "", "", "With -product, only versions lower than this"),
+		flagSet.StringVarP(&opts.SQL, "sql", "", "", "Run a raw SQL query"),
+	)
+
+	flagSet.CreateGroup("output", "Output",
+		flagSet.BoolVarP(&opts.Latest, "latest", "", false, "Only use each host's most recent scan"),
+		flagSet.BoolVarP(&opts.JSON, "json", "", false, "Print results as JSON"),
+	)
+
+	if err := flagSet.Parse(subcommandArgs(args)...); err != nil {
+		logger.Error("Failed parsing flags: %s", err)
+		os.Exit(1)
+	}
+
+	return opts
+}
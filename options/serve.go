@@ -0,0 +1,55 @@
+package options
+
+import (
+	"os"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/projectdiscovery/goflags"
+)
+
+// ServeOptions are the flags of the serve subcommand.
+type ServeOptions struct {
+	Options
+
+	Addr             string
+	DataDir          string
+	Token            string
+	MaxScans         int
+	MaxThreads       int
+	MaxTimeout       int
+	AllowCustomFlags bool
+	FlagPolicy       string
+	Metrics          bool
+}
+
+// ParseServeOptions parses the arguments following "chainmap serve".
+func ParseServeOptions(args []string) *ServeOptions {
+	opts := &ServeOptions{}
+
+	flagSet := goflags.NewFlagSet()
+
+	flagSet.SetDescription("Run the chainmap REST API")
+
+	flagSet.CreateGroup("server", "Server",
+		flagSet.StringVarP(&opts.Addr, "listen", "", "127.0.0.1:8080", "Address to listen on"),
+		flagSet.StringVarP(&opts.DataDir, "data-dir", "", "chainmap-server", "Directory for the job store and scan results"),
+		flagSet.StringVarP(&opts.Token, "token", "", os.Getenv("CHAINMAP_API_TOKEN"), "Bearer token required by the API (default $CHAINMAP_API_TOKEN)"),
+		flagSet.IntVarP(&opts.MaxScans, "max-scans", "", 1, "Number of scans run at the same time"),
+		flagSet.IntVarP(&opts.MaxThreads, "max-threads", "", 50, "Most threads a scan request may ask for"),
+		flagSet.IntVarP(&opts.MaxTimeout, "max-timeout", "", 720, "Longest per-job timeout in minutes a scan request may ask for"),
+		flagSet.BoolVarP(&opts.AllowCustomFlags, "allow-custom-flags", "", false, "Allow clients to pass their own nmap flags"),
+		flagSet.StringVarP(&opts.FlagPolicy, "flag-policy", "", "", "YAML allowlist/denylist for custom nmap flags"),
+		flagSet.BoolVarP(&opts.Metrics, "metrics", "", false, "Expose Prometheus metrics at /metrics (same token as the API)"),
+	)
+
+	flagSet.CreateGroup("misc", "Optimization", logFlags(flagSet, &opts.Options)...)
+
+	if err := flagSet.Parse(subcommandArgs(args)...); err != nil {
+		logger.Error("Failed parsing flags: %s", err)
+		os.Exit(1)
+	}
+
+	configureLogger(&opts.Options)
+
+	return opts
+}
@@ -0,0 +1,59 @@
+package options
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/projectdiscovery/goflags"
+)
+
+// WorkerOptions are the flags of the worker subcommand.
+type WorkerOptions struct {
+	Options
+
+	// CoordinatorURL is where leases come from. Options.Coordinator is the
+	// listen address of a coordinating scan and stays empty for workers.
+	CoordinatorURL string
+	Name           string
+}
+
+// ParseWorkerOptions parses the arguments following "chainmap worker".
+func ParseWorkerOptions(args []string) *WorkerOptions {
+	opts := &WorkerOptions{}
+
+	hostname, _ := os.Hostname()
+
+	flagSet := goflags.NewFlagSet()
+
+	flagSet.SetDescription("Scan jobs handed out by a chainmap coordinator")
+
+	flagSet.CreateGroup("cluster", "Cluster",
+		flagSet.StringVarP(&opts.CoordinatorURL, "coordinator", "", "", "Coordinator URL, e.g. http://10.0.0.5:9090"),
+		flagSet.StringVarP(&opts.ClusterToken, "cluster-token", "", os.Getenv("CHAINMAP_CLUSTER_TOKEN"), "Bearer token for the coordinator (default $CHAINMAP_CLUSTER_TOKEN)"),
+		flagSet.StringVarP(&opts.Name, "name", "", fmt.Sprintf("%s-%d", hostname, os.Getpid()), "Worker name shown in the coordinator manifest"),
+		flagSet.IntVarP(&opts.Threads, "threads", "c", 5, "Number of jobs scanned at the same time"),
+		flagSet.BoolVarP(&opts.AutoDowngrade, "auto-downgrade", "", false, "Rewrite leased flags that need root when nmap has no raw socket access"),
+		flagSet.StringVarP(&opts.FlagPolicy, "flag-policy", "", "", "YAML allowlist/denylist applied to leased nmap flags"),
+		flagSet.StringVarP(&opts.RunAs, "run-as", "", "", "When started as root, drop to this user after setup, keeping only the capabilities nmap needs"),
+		flagSet.BoolVarP(&opts.Sandbox, "sandbox", "", false, "Run nmap in a Landlock sandbox that may only write to its output directory"),
+		flagSet.StringVarP(&opts.StateDir, "state-dir", "", "chainmap-state", "Directory for per-job nmap logs"),
+		flagSet.StringVarP(&opts.MetricsAddr, "metrics-addr", "", "", "Serve Prometheus metrics on this address under /metrics"),
+	)
+
+	flagSet.CreateGroup("misc", "Optimization", logFlags(flagSet, &opts.Options)...)
+
+	if err := flagSet.Parse(subcommandArgs(args)...); err != nil {
+		logger.Error("Failed parsing flags: %s", err)
+		os.Exit(1)
+	}
+
+	configureLogger(&opts.Options)
+
+	if opts.CoordinatorURL == "" {
+		logger.Error("A -coordinator URL is required")
+		os.Exit(1)
+	}
+
+	return opts
+}
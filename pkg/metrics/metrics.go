@@ -0,0 +1,211 @@
+// Package metrics keeps chainmap's counters, gauges and histograms and
+// renders them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Default is the registry the runner records into.
+var Default = NewRegistry()
+
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds metrics in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the text exposition format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// vec stores one value per combination of label values.
+type vec struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newVec(name, help, kind string, labels []string) *vec {
+	return &vec{name: name, help: help, kind: kind, labels: labels, values: make(map[string]float64)}
+}
+
+func (v *vec) add(delta float64, values []string) {
+	key := v.key(values)
+	v.mu.Lock()
+	v.values[key] += delta
+	v.mu.Unlock()
+}
+
+func (v *vec) set(value float64, values []string) {
+	key := v.key(values)
+	v.mu.Lock()
+	v.values[key] = value
+	v.mu.Unlock()
+}
+
+func (v *vec) key(values []string) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (v *vec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	if len(v.labels) == 0 {
+		fmt.Fprintf(w, "%s %s\n", v.name, formatValue(v.values[""]))
+		return
+	}
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, strings.Split(k, "\xff")), formatValue(v.values[k]))
+	}
+}
+
+// Counter only goes up.
+type Counter struct{ v *vec }
+
+// NewCounter registers a counter with the given label names.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVec(name, help, "counter", labels)}
+	r.register(c.v)
+	return c
+}
+
+// Inc adds one for the given label values.
+func (c *Counter) Inc(values ...string) { c.v.add(1, values) }
+
+// Add adds delta, which must not be negative.
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.add(delta, values)
+}
+
+// Gauge can go up and down.
+type Gauge struct{ v *vec }
+
+// NewGauge registers a gauge with the given label names.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newVec(name, help, "gauge", labels)}
+	r.register(g.v)
+	return g
+}
+
+// Add changes the gauge by delta.
+func (g *Gauge) Add(delta float64, values ...string) { g.v.add(delta, values) }
+
+// Set replaces the gauge value.
+func (g *Gauge) Set(value float64, values ...string) { g.v.set(value, values) }
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram registers a histogram with the given upper bounds.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	h := &Histogram{name: name, help: help, buckets: b, counts: make([]uint64, len(b))}
+	r.register(h)
+	return h
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, upper := range h.buckets {
+		if value <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatValue(upper), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n%s_count %d\n", h.name, formatValue(h.sum), h.name, h.count)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	jobs := r.NewCounter("jobs_total", "Finished jobs.", "status")
+	running := r.NewGauge("running", "Running jobs.")
+	duration := r.NewHistogram("duration_seconds", "Job duration.", []float64{10, 1})
+
+	jobs.Inc("ok")
+	jobs.Inc("ok")
+	jobs.Inc(`we"ird`)
+	running.Add(3)
+	running.Add(-1)
+	duration.Observe(0.5)
+	duration.Observe(5)
+	duration.Observe(60)
+
+	var b strings.Builder
+	r.Write(&b)
+	want := `# HELP jobs_total Finished jobs.
+# TYPE jobs_total counter
+jobs_total{status="ok"} 2
+jobs_total{status="we\"ird"} 1
+# HELP running Running jobs.
+# TYPE running gauge
+running 2
+# HELP duration_seconds Job duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="1"} 1
+duration_seconds_bucket{le="10"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 65.5
+duration_seconds_count 3
+`
+	if b.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
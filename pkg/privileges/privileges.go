@@ -0,0 +1,141 @@
+// Package privileges detects whether nmap can use raw sockets, either by
+// running as root or through Linux capabilities.
+package privileges
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Linux capability numbers nmap needs for raw socket scans.
+const (
+	capNetAdmin = 12
+	capNetRaw   = 13
+)
+
+// Capabilities describes the raw socket access nmap gets when chainmap
+// starts it.
+type Capabilities struct {
+	// Root is set when chainmap runs with an effective UID of 0.
+	Root bool
+	// NmapPath is the resolved nmap binary, empty when it is not in PATH.
+	NmapPath string
+	// Setuid is set when the nmap binary is setuid root.
+	Setuid bool
+	// NetRaw and NetAdmin report CAP_NET_RAW and CAP_NET_ADMIN, held by
+	// chainmap or granted to nmap as file capabilities.
+	NetRaw   bool
+	NetAdmin bool
+	// Ambient is set when chainmap holds both capabilities without being
+	// root, as with -run-as, and passes them on to nmap.
+	Ambient bool
+}
+
+// Privileged reports whether nmap can send raw packets.
+func (c Capabilities) Privileged() bool {
+	return c.Root || c.Setuid || (c.NetRaw && c.NetAdmin)
+}
+
+// NeedsPrivilegedFlag reports whether nmap only gets raw socket access
+// through capabilities. nmap checks its UID and has to be told with
+// --privileged that it may use them.
+func (c Capabilities) NeedsPrivilegedFlag() bool {
+	return !c.Root && !c.Setuid && c.NetRaw && c.NetAdmin
+}
+
+// Sandboxed returns the privileges nmap keeps inside the sandbox. Unless
+// chainmap runs as root the sandbox sets no_new_privs, under which a setuid
+// nmap and its file capabilities no longer take effect.
+func (c Capabilities) Sandboxed() Capabilities {
+	if c.Root || c.Ambient {
+		c.Setuid = false
+		return c
+	}
+	c.Setuid, c.NetRaw, c.NetAdmin = false, false, false
+	return c
+}
+
+func (c Capabilities) String() string {
+	switch {
+	case c.Root:
+		return "root"
+	case c.Setuid:
+		return "setuid root nmap"
+	case c.NetRaw || c.NetAdmin:
+		var caps []string
+		if c.NetRaw {
+			caps = append(caps, "CAP_NET_RAW")
+		}
+		if c.NetAdmin {
+			caps = append(caps, "CAP_NET_ADMIN")
+		}
+		return strings.Join(caps, ",")
+	default:
+		return "unprivileged"
+	}
+}
+
+// Detect reports the privileges nmap at nmapPath runs with. nmapPath may be
+// empty when nmap is not installed, in which case only the process is
+// checked.
+func Detect(nmapPath string) Capabilities {
+	c := Capabilities{Root: os.Geteuid() == 0, NmapPath: nmapPath}
+	if mask, ok := heldCaps(); ok && !c.Root {
+		c.NetRaw = mask&(1<<capNetRaw) != 0
+		c.NetAdmin = mask&(1<<capNetAdmin) != 0
+		c.Ambient = c.NetRaw && c.NetAdmin
+	}
+	if nmapPath == "" {
+		return c
+	}
+	if info, err := os.Stat(nmapPath); err == nil && info.Mode()&os.ModeSetuid != 0 {
+		c.Setuid = fileOwnedByRoot(info)
+	}
+	if mask, ok := fileCaps(nmapPath); ok {
+		c.NetRaw = c.NetRaw || mask&(1<<capNetRaw) != 0
+		c.NetAdmin = c.NetAdmin || mask&(1<<capNetAdmin) != 0
+	}
+	return c
+}
+
+// parseStatusCaps returns the capability mask of field ("CapAmb",
+// "CapEff", ...) from /proc/<pid>/status.
+func parseStatusCaps(r io.Reader, field string) (uint64, bool) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || name != field {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		return mask, err == nil
+	}
+	return 0, false
+}
+
+// vfsCapFlagsEffective is set in a file capability when the permitted
+// capabilities are also effective on exec.
+const vfsCapFlagsEffective = 0x1
+
+// parseFileCaps decodes the security.capability extended attribute and
+// returns the capabilities the file gets on exec. Capabilities that are
+// only permitted are ignored since nmap does not raise them itself.
+func parseFileCaps(data []byte) (uint64, bool) {
+	if len(data) < 12 {
+		return 0, false
+	}
+	magic := binary.LittleEndian.Uint32(data[0:4])
+	if magic&vfsCapFlagsEffective == 0 {
+		return 0, true
+	}
+	mask := uint64(binary.LittleEndian.Uint32(data[4:8]))
+	// Revisions 2 and 3 store the upper 32 capabilities after the first set.
+	if len(data) >= 20 {
+		mask |= uint64(binary.LittleEndian.Uint32(data[12:16])) << 32
+	}
+	return mask, true
+}
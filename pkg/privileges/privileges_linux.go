@@ -0,0 +1,86 @@
+package privileges
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	linuxCapabilityVersion3 = 0x20080522
+	prCapAmbient            = 47
+	prCapAmbientRaise       = 2
+)
+
+// heldCaps returns the permitted capabilities of chainmap, which it passes
+// on to nmap as ambient capabilities.
+func heldCaps() (uint64, bool) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	return parseStatusCaps(f, "CapPrm")
+}
+
+// Inherit starts cmd with CAP_NET_RAW and CAP_NET_ADMIN as ambient
+// capabilities when chainmap holds them without being root.
+func (c Capabilities) Inherit(cmd *exec.Cmd) {
+	if !c.Ambient {
+		return
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.AmbientCaps = []uintptr{capNetRaw, capNetAdmin}
+}
+
+// raiseAmbient makes CAP_NET_RAW and CAP_NET_ADMIN ambient on the calling
+// thread, so that a program it executes keeps them. A dependency resets the
+// inheritable set while chainmap starts, which drops CAP_NET_ADMIN from the
+// ambient set it was started with.
+func raiseAmbient() error {
+	hdr := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityVersion3}
+	var data [2]struct{ effective, permitted, inheritable uint32 }
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPGET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to read capabilities: %w", errno)
+	}
+	data[0].inheritable |= 1<<capNetRaw | 1<<capNetAdmin
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return fmt.Errorf("failed to set capabilities: %w", errno)
+	}
+	for _, c := range []uintptr{capNetRaw, capNetAdmin} {
+		if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientRaise, c, 0, 0, 0); errno != 0 {
+			return fmt.Errorf("failed to raise ambient capabilities: %w", errno)
+		}
+	}
+	return nil
+}
+
+// fileCaps returns the file capabilities of the binary at path.
+func fileCaps(path string) (uint64, bool) {
+	buf := make([]byte, 64)
+	n, err := syscall.Getxattr(path, "security.capability", buf)
+	if err != nil {
+		return 0, false
+	}
+	return parseFileCaps(buf[:n])
+}
+
+func fileOwnedByRoot(info os.FileInfo) bool {
+	uid, ok := fileOwner(info)
+	return ok && uid == 0
+}
+
+func fileOwner(info os.FileInfo) (uint32, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return stat.Uid, true
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package privileges
+
+import (
+	"os"
+	"os/exec"
+)
+
+// Capabilities are Linux only; elsewhere nmap needs root.
+
+func heldCaps() (uint64, bool) {
+	return 0, false
+}
+
+// Inherit does nothing outside Linux.
+func (c Capabilities) Inherit(cmd *exec.Cmd) {}
+
+func fileCaps(path string) (uint64, bool) {
+	return 0, false
+}
+
+func fileOwnedByRoot(info os.FileInfo) bool {
+	return false
+}
+
+func fileOwner(info os.FileInfo) (uint32, bool) {
+	return 0, false
+}
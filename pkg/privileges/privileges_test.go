@@ -0,0 +1,92 @@
+package privileges
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestParseStatusCaps(t *testing.T) {
+	status := "Name:\tchainmap\nCapInh:\t0000000000000000\nCapAmb:\t0000000000003000\n"
+	mask, ok := parseStatusCaps(strings.NewReader(status), "CapAmb")
+	if !ok || mask != 1<<capNetRaw|1<<capNetAdmin {
+		t.Errorf("parseStatusCaps() = %x, %v", mask, ok)
+	}
+	if _, ok := parseStatusCaps(strings.NewReader("Name:\tchainmap\n"), "CapAmb"); ok {
+		t.Error("parseStatusCaps() found a missing field")
+	}
+}
+
+func TestParseFileCaps(t *testing.T) {
+	// vfs_cap_data revision 2 as written by setcap cap_net_raw,cap_net_admin+eip.
+	xattr := func(magic uint32, permitted uint32) []byte {
+		data := make([]byte, 20)
+		binary.LittleEndian.PutUint32(data[0:], magic)
+		binary.LittleEndian.PutUint32(data[4:], permitted)
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want uint64
+		ok   bool
+	}{
+		{"Effective", xattr(0x02000001, 1<<capNetRaw|1<<capNetAdmin), 1<<capNetRaw | 1<<capNetAdmin, true},
+		{"Permitted only", xattr(0x02000000, 1<<capNetRaw|1<<capNetAdmin), 0, true},
+		{"Truncated", []byte{1, 0, 0, 2}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseFileCaps(tt.data)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseFileCaps() = %x, %v, want %x, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		caps       Capabilities
+		privileged bool
+		flag       bool
+		str        string
+	}{
+		{Capabilities{Root: true}, true, false, "root"},
+		{Capabilities{Setuid: true}, true, false, "setuid root nmap"},
+		{Capabilities{NetRaw: true, NetAdmin: true}, true, true, "CAP_NET_RAW,CAP_NET_ADMIN"},
+		{Capabilities{NetRaw: true}, false, false, "CAP_NET_RAW"},
+		{Capabilities{}, false, false, "unprivileged"},
+	}
+	for _, tt := range tests {
+		if got := tt.caps.Privileged(); got != tt.privileged {
+			t.Errorf("%s: Privileged() = %v", tt.str, got)
+		}
+		if got := tt.caps.NeedsPrivilegedFlag(); got != tt.flag {
+			t.Errorf("%s: NeedsPrivilegedFlag() = %v", tt.str, got)
+		}
+		if got := tt.caps.String(); got != tt.str {
+			t.Errorf("String() = %q, want %q", got, tt.str)
+		}
+	}
+}
+
+func TestSandboxed(t *testing.T) {
+	tests := []struct {
+		name string
+		caps Capabilities
+		want Capabilities
+	}{
+		{"Root", Capabilities{Root: true, Setuid: true}, Capabilities{Root: true}},
+		{"Ambient", Capabilities{NetRaw: true, NetAdmin: true, Ambient: true}, Capabilities{NetRaw: true, NetAdmin: true, Ambient: true}},
+		{"File caps", Capabilities{NetRaw: true, NetAdmin: true}, Capabilities{}},
+		{"Setuid", Capabilities{Setuid: true}, Capabilities{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.caps.Sandboxed(); got != tt.want {
+				t.Errorf("Sandboxed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package privileges
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Command returns a command running the chainmap executable with args as
+// u. Of root's capabilities it keeps only CAP_NET_RAW and CAP_NET_ADMIN, as
+// ambient capabilities that nmap inherits.
+func (u *User) Command(args ...string) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(self, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential:  &syscall.Credential{Uid: u.UID, Gid: u.GID, Groups: u.Groups},
+		AmbientCaps: []uintptr{capNetRaw, capNetAdmin},
+	}
+	cmd.Env = append(os.Environ(), "HOME="+u.Home, "USER="+u.Name, "LOGNAME="+u.Name)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd, nil
+}
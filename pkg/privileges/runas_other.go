@@ -0,0 +1,14 @@
+//go:build !linux
+
+package privileges
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Command is only supported on Linux, which can hand capabilities to an
+// unprivileged user.
+func (u *User) Command(args ...string) (*exec.Cmd, error) {
+	return nil, errors.New("-run-as is only supported on Linux")
+}
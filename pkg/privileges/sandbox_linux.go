@@ -0,0 +1,132 @@
+package privileges
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock system calls, numbered alike on every architecture.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1
+	landlockRulePathBeneath      = 1
+
+	prSetNoNewPrivs = 38
+)
+
+// Landlock filesystem access rights up to ABI 3.
+const (
+	accessExecute = 1 << iota
+	accessWriteFile
+	accessReadFile
+	accessReadDir
+	accessRemoveDir
+	accessRemoveFile
+	accessMakeChar
+	accessMakeDir
+	accessMakeReg
+	accessMakeSock
+	accessMakeFifo
+	accessMakeBlock
+	accessMakeSym
+	accessRefer
+	accessTruncate
+)
+
+// SandboxVersion returns the kernel's Landlock ABI version, or an error
+// when Landlock is not available.
+func SandboxVersion() (int, error) {
+	v, _, errno := syscall.Syscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return 0, fmt.Errorf("landlock is not available: %w", errno)
+	}
+	return int(v), nil
+}
+
+// SandboxExec confines the process to reading files anywhere and writing
+// only below dir and to /dev/null, then replaces it with argv. It only
+// returns on error.
+func SandboxExec(dir string, argv []string) error {
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	abi, err := SandboxVersion()
+	if err != nil {
+		return err
+	}
+	handled := uint64(accessMakeSym<<1 - 1)
+	if abi >= 2 {
+		handled |= accessRefer
+	}
+	if abi >= 3 {
+		handled |= accessTruncate
+	}
+
+	attr := struct{ handledAccessFS uint64 }{handled}
+	fd, _, errno := syscall.Syscall(sysLandlockCreateRuleset, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to create landlock ruleset: %w", errno)
+	}
+	ruleset := int(fd)
+	defer syscall.Close(ruleset)
+
+	rules := []struct {
+		path   string
+		access uint64
+	}{
+		{"/", accessExecute | accessReadFile | accessReadDir},
+		{dir, handled},
+		{"/dev/null", (accessReadFile | accessWriteFile | accessTruncate) & handled},
+	}
+	for _, rule := range rules {
+		if err := addRule(ruleset, rule.path, rule.access); err != nil {
+			return err
+		}
+	}
+
+	// Landlock applies to the calling thread, which must be the one that
+	// executes nmap.
+	runtime.LockOSThread()
+	// Without CAP_SYS_ADMIN Landlock needs no_new_privs, which also stops
+	// setuid bits and file capabilities from taking effect.
+	if os.Geteuid() != 0 {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+			return fmt.Errorf("failed to set no_new_privs: %w", errno)
+		}
+		if Detect("").Ambient {
+			if err := raiseAmbient(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, _, errno := syscall.RawSyscall(sysLandlockRestrictSelf, uintptr(ruleset), 0, 0); errno != 0 {
+		return fmt.Errorf("failed to enforce landlock ruleset: %w", errno)
+	}
+	return syscall.Exec(path, argv, os.Environ())
+}
+
+func addRule(ruleset int, path string, access uint64) error {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer syscall.Close(fd)
+
+	attr := struct {
+		allowedAccess uint64
+		parentFd      int32
+	}{access, int32(fd)}
+	_, _, errno := syscall.Syscall6(sysLandlockAddRule, uintptr(ruleset), landlockRulePathBeneath, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("failed to add landlock rule for %s: %w", path, errno)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package privileges
+
+import "errors"
+
+var errNoSandbox = errors.New("the sandbox needs Linux with Landlock")
+
+func SandboxVersion() (int, error) {
+	return 0, errNoSandbox
+}
+
+func SandboxExec(dir string, argv []string) error {
+	return errNoSandbox
+}
@@ -0,0 +1,98 @@
+package privileges
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// User is an account chainmap drops to with -run-as.
+type User struct {
+	Name   string
+	Home   string
+	UID    uint32
+	GID    uint32
+	Groups []uint32
+}
+
+// LookupUser resolves a user name or numeric UID.
+func LookupUser(name string) (*User, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if u, err = user.LookupId(name); err != nil {
+			return nil, fmt.Errorf("unknown user %q", name)
+		}
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has no numeric UID", name)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s has no numeric GID", name)
+	}
+	res := &User{Name: u.Username, Home: u.HomeDir, UID: uint32(uid), GID: uint32(gid)}
+	if ids, err := u.GroupIds(); err == nil {
+		for _, id := range ids {
+			if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+				res.Groups = append(res.Groups, uint32(g))
+			}
+		}
+	}
+	return res, nil
+}
+
+// Claim readies the paths of a run for u without handing over anything
+// chainmap did not create. Missing directories in dirs are created and
+// only the directory itself is given to u; missing files are left for u to
+// create. A directory or file that already exists must belong to u, since
+// its contents were not made by chainmap.
+func (u *User) Claim(dirs, files []string) error {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if err := u.owns(dir); !errors.Is(err, fs.ErrNotExist) {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		if err := u.Chown(dir); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if err := u.owns(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Chown hands the single file or directory at path, not what is below it,
+// to u.
+func (u *User) Chown(path string) error {
+	return os.Lchown(path, int(u.UID), int(u.GID))
+}
+
+// owns checks that path exists and belongs to u.
+func (u *User) owns(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if uid, ok := fileOwner(info); !ok || uid != u.UID {
+		return fmt.Errorf("%s already exists and does not belong to %s", path, u.Name)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package privileges
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupUser(t *testing.T) {
+	byName, err := LookupUser("root")
+	if err != nil {
+		t.Skipf("no root user: %v", err)
+	}
+	if byName.UID != 0 || byName.GID != 0 {
+		t.Errorf("LookupUser(root) = %+v", byName)
+	}
+	byID, err := LookupUser("0")
+	if err != nil || byID.Name != byName.Name {
+		t.Errorf("LookupUser(0) = %+v, %v", byID, err)
+	}
+	if _, err := LookupUser("no-such-chainmap-user"); err == nil {
+		t.Error("LookupUser() accepted an unknown user")
+	}
+}
+
+func TestClaim(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "scan.xml")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Handing paths to their current owner needs no privileges; another
+	// UID stands for a user that owns none of them.
+	self := &User{Name: "self", UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	other := &User{Name: "other", UID: uint32(os.Getuid()) + 1, GID: uint32(os.Getgid())}
+
+	tests := []struct {
+		name    string
+		user    *User
+		dirs    []string
+		files   []string
+		wantErr bool
+	}{
+		{"New directories", self, []string{filepath.Join(dir, "state", "jobs"), ""}, nil, false},
+		{"Own directory", self, []string{dir}, []string{file}, false},
+		{"Missing files", other, nil, []string{filepath.Join(dir, "missing.json"), ""}, false},
+		{"Foreign directory", other, []string{dir}, nil, true},
+		{"Foreign file", other, nil, []string{file}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.user.Claim(tt.dirs, tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Claim() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, d := range tt.dirs {
+				if fi, err := os.Stat(d); d != "" && (err != nil || !fi.IsDir()) {
+					t.Errorf("%s not created: %v", d, err)
+				}
+			}
+		})
+	}
+}
+
+func TestChown(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "chainmap.log")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	u := &User{UID: uint32(os.Getuid()), GID: uint32(os.Getgid())}
+	if err := u.Chown(file); err != nil {
+		t.Errorf("Chown() = %v", err)
+	}
+	if err := u.Chown(filepath.Join(filepath.Dir(file), "missing")); err == nil {
+		t.Error("Chown() accepted a missing file")
+	}
+}
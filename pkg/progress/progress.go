@@ -0,0 +1,249 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Tracker keeps the state of a scan run and renders it to stderr. On a TTY it
+// redraws a live block with one line per worker; otherwise it prints a single
+// status line every interval.
+type Tracker struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	interval time.Duration
+	start    time.Time
+
+	total   int
+	running int
+	done    int
+	failed  int
+	skipped int
+	workers map[int]*workerState
+
+	// Concurrency, when set, reports the current worker limit.
+	Concurrency func() int
+
+	drawn int
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+type workerState struct {
+	host    string
+	started time.Time
+	phase   string
+	percent float64
+}
+
+// New creates a tracker for total jobs writing to stderr.
+func New(total int, interval time.Duration) *Tracker {
+	return &Tracker{
+		out:      os.Stderr,
+		tty:      isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()),
+		interval: interval,
+		total:    total,
+		workers:  make(map[int]*workerState),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start begins rendering in the background.
+func (t *Tracker) Start() {
+	t.start = time.Now()
+	refresh := t.interval
+	if t.tty {
+		refresh = 500 * time.Millisecond
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.render()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts rendering and leaves a final status line behind.
+func (t *Tracker) Stop() {
+	close(t.stop)
+	t.wg.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clear()
+	fmt.Fprintln(t.out, t.statusLine())
+}
+
+// JobStarted marks host as running on worker and returns a writer that
+// parses nmap's --stats-every output for that worker. The caller closes it
+// once nmap exits.
+func (t *Tracker) JobStarted(worker int, host string) io.WriteCloser {
+	t.mu.Lock()
+	t.running++
+	t.workers[worker] = &workerState{host: host, started: time.Now()}
+	t.mu.Unlock()
+	return newStatsWriter(t, worker)
+}
+
+// JobFinished marks the job on worker as done or failed.
+func (t *Tracker) JobFinished(worker int, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	if failed {
+		t.failed++
+	} else {
+		t.done++
+	}
+	delete(t.workers, worker)
+}
+
+// JobSkipped counts a job that was never started.
+func (t *Tracker) JobSkipped() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.skipped++
+}
+
+// JobRequeued removes the job on worker without counting it, for jobs that
+// will be retried elsewhere.
+func (t *Tracker) JobRequeued(worker int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	delete(t.workers, worker)
+}
+
+// Wrap returns a writer that clears the live block before writing to w and
+// redraws it afterwards, so log lines don't tear the display.
+func (t *Tracker) Wrap(w io.Writer) io.Writer {
+	if !t.tty {
+		return w
+	}
+	return &wrappedWriter{t: t, w: w}
+}
+
+type wrappedWriter struct {
+	t *Tracker
+	w io.Writer
+}
+
+func (ww *wrappedWriter) Write(p []byte) (int, error) {
+	ww.t.mu.Lock()
+	defer ww.t.mu.Unlock()
+	ww.t.clear()
+	n, err := ww.w.Write(p)
+	ww.t.draw()
+	return n, err
+}
+
+func (t *Tracker) update(worker int, phase string, percent float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if ws, ok := t.workers[worker]; ok {
+		ws.phase = phase
+		ws.percent = percent
+	}
+}
+
+func (t *Tracker) render() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tty {
+		t.clear()
+		t.draw()
+		return
+	}
+	fmt.Fprintln(t.out, t.statusLine())
+}
+
+// clear erases the previously drawn block. Callers hold t.mu.
+func (t *Tracker) clear() {
+	if t.drawn == 0 {
+		return
+	}
+	fmt.Fprintf(t.out, "\033[%dA\033[J", t.drawn)
+	t.drawn = 0
+}
+
+// draw prints the live block. Callers hold t.mu.
+func (t *Tracker) draw() {
+	if !t.tty || t.start.IsZero() {
+		return
+	}
+	lines := []string{t.statusLine()}
+
+	ids := make([]int, 0, len(t.workers))
+	for id := range t.workers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		ws := t.workers[id]
+		line := fmt.Sprintf("  #%-2d %-30s %8s", id+1, ws.host, formatDuration(time.Since(ws.started)))
+		if ws.phase != "" {
+			line += fmt.Sprintf("  %s %.1f%%", ws.phase, ws.percent)
+		}
+		lines = append(lines, line)
+	}
+
+	fmt.Fprintln(t.out, strings.Join(lines, "\n"))
+	t.drawn = len(lines)
+}
+
+// statusLine summarises the run. Callers hold t.mu.
+func (t *Tracker) statusLine() string {
+	elapsed := time.Since(t.start)
+	queued := t.total - t.running - t.done - t.failed - t.skipped
+
+	line := fmt.Sprintf("[PROGRESS] %d/%d done, %d failed, %d running, %d queued",
+		t.done+t.failed, t.total, t.failed, t.running, queued)
+	if t.skipped > 0 {
+		line += fmt.Sprintf(", %d not scanned", t.skipped)
+	}
+	if t.Concurrency != nil {
+		line += fmt.Sprintf(" | concurrency %d", t.Concurrency())
+	}
+	line += fmt.Sprintf(" | elapsed %s", formatDuration(elapsed))
+	if eta, ok := t.eta(elapsed); ok {
+		line += fmt.Sprintf(" | ETA %s", formatDuration(eta))
+	}
+	return line
+}
+
+// eta extrapolates from finished jobs plus the percent done of running ones.
+func (t *Tracker) eta(elapsed time.Duration) (time.Duration, bool) {
+	if t.total == 0 {
+		return 0, false
+	}
+	completed := float64(t.done + t.failed)
+	for _, ws := range t.workers {
+		completed += ws.percent / 100
+	}
+	fraction := completed / float64(t.total)
+	if fraction <= 0 || fraction >= 1 {
+		return 0, false
+	}
+	return time.Duration(float64(elapsed) * (1 - fraction) / fraction), true
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
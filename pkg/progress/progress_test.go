@@ -0,0 +1,109 @@
+package progress
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatsLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		phase   string
+		percent float64
+		ok      bool
+	}{
+		{"SYN Stealth Scan Timing: About 45.23% done; ETC: 12:34 (0:00:10 remaining)", "SYN Stealth Scan", 45.23, true},
+		{"Service scan Timing: About 80.00% done; ETC: 12:00 (0:00:01 remaining)", "Service scan", 80, true},
+		{"NSE Timing: About 99.50% done; ETC: 12:00 (0:00:00 remaining)", "NSE", 99.5, true},
+		{"Stats: 0:00:05 elapsed; 0 hosts completed (1 up), 1 undergoing SYN Stealth Scan", "", 0, false},
+		{"Starting Nmap 7.94", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			phase, percent, ok := ParseStatsLine(tt.line)
+			if phase != tt.phase || percent != tt.percent || ok != tt.ok {
+				t.Errorf("ParseStatsLine() = %q, %v, %v, want %q, %v, %v", phase, percent, ok, tt.phase, tt.percent, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	var out bytes.Buffer
+	tr := New(4, time.Hour)
+	tr.out, tr.tty = &out, false
+	tr.Concurrency = func() int { return 2 }
+	tr.Start()
+
+	w := tr.JobStarted(0, "10.0.0.1")
+	w.Write([]byte("Starting Nmap\nSYN Stealth Scan Timing: About 50.00% done; ETC: 12:00 (0:00:10 remaining)\n"))
+	w.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		tr.mu.Lock()
+		percent := tr.workers[0].percent
+		tr.mu.Unlock()
+		if percent == 50 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stats line was not applied")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	tr.JobStarted(1, "10.0.0.2").Close()
+	tr.JobFinished(1, true)
+	tr.JobStarted(1, "10.0.0.3").Close()
+	tr.JobRequeued(1)
+	tr.JobSkipped()
+
+	tr.mu.Lock()
+	line := tr.statusLine()
+	tr.mu.Unlock()
+	want := "[PROGRESS] 1/4 done, 1 failed, 1 running, 1 queued, 1 not scanned | concurrency 2 | elapsed "
+	if !strings.HasPrefix(line, want) {
+		t.Errorf("statusLine() = %q, want prefix %q", line, want)
+	}
+	if !strings.Contains(line, "ETA") {
+		t.Errorf("statusLine() = %q, want an ETA", line)
+	}
+
+	tr.JobFinished(0, false)
+	tr.Stop()
+	if got := out.String(); !strings.HasPrefix(got, "[PROGRESS] 2/4 done, 1 failed, 0 running") {
+		t.Errorf("final line = %q", got)
+	}
+}
+
+func TestETA(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int
+		done    int
+		percent float64
+		want    time.Duration
+		ok      bool
+	}{
+		{"No jobs", 0, 0, 0, 0, false},
+		{"Nothing done", 4, 0, 0, 0, false},
+		{"Half done", 4, 2, 0, time.Minute, true},
+		{"Running job counts", 4, 1, 100, time.Minute, true},
+		{"All done", 4, 4, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := New(tt.total, time.Second)
+			tr.done = tt.done
+			if tt.percent > 0 {
+				tr.workers[0] = &workerState{percent: tt.percent}
+			}
+			got, ok := tr.eta(time.Minute)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("eta() = %s, %v, want %s, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
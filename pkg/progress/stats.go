@@ -0,0 +1,52 @@
+package progress
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// timingLine matches nmap's --stats-every progress lines, e.g.
+// "SYN Stealth Scan Timing: About 45.23% done; ETC: 12:34 (0:00:10 remaining)".
+var timingLine = regexp.MustCompile(`^(.+?) Timing: About ([0-9.]+)% done`)
+
+// ParseStatsLine extracts the phase and percent done from an nmap stats line.
+func ParseStatsLine(line string) (phase string, percent float64, ok bool) {
+	m := timingLine.FindStringSubmatch(line)
+	if m == nil {
+		return "", 0, false
+	}
+	percent, err := strconv.ParseFloat(m[2], 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return m[1], percent, true
+}
+
+// statsWriter feeds nmap stdout line by line into the tracker for one worker.
+type statsWriter struct {
+	pw *io.PipeWriter
+}
+
+func newStatsWriter(t *Tracker, worker int) *statsWriter {
+	pr, pw := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			if phase, percent, ok := ParseStatsLine(scanner.Text()); ok {
+				t.update(worker, phase, percent)
+			}
+		}
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return &statsWriter{pw: pw}
+}
+
+func (w *statsWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *statsWriter) Close() error {
+	return w.pw.Close()
+}
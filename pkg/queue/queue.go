@@ -0,0 +1,68 @@
+// Package queue connects chainmap to external work queues, so targets can be
+// consumed and per-host results published by a long-running process.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Message is one item taken from a queue. It stays claimed until Ack removes
+// it for good or Nack hands it back for another consumer.
+type Message struct {
+	Body []byte
+
+	ack  func() error
+	nack func() error
+}
+
+// Ack marks the message as processed.
+func (m *Message) Ack() error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack()
+}
+
+// Nack returns the message to the queue.
+func (m *Message) Nack() error {
+	if m.nack == nil {
+		return nil
+	}
+	return m.nack()
+}
+
+// Queue is a FIFO of opaque messages.
+type Queue interface {
+	// Pop blocks until a message is available or ctx is done.
+	Pop(ctx context.Context) (*Message, error)
+	// Push appends body to the queue.
+	Push(ctx context.Context, body []byte) error
+	Close() error
+}
+
+// Open returns the queue named by rawURL:
+//
+//	dir:///var/spool/chainmap/in   spool directory, one message per file
+//	/var/spool/chainmap/in         same, as a plain path
+//	redis://:pass@host:6379/0?key=chainmap:targets
+//	                               Redis list (LPUSH/RPUSH in, BLPOP out)
+func Open(rawURL string) (Queue, error) {
+	if !strings.Contains(rawURL, "://") {
+		return OpenSpool(rawURL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid queue URL: %w", err)
+	}
+	switch u.Scheme {
+	case "dir", "file":
+		return OpenSpool(u.Host + u.Path)
+	case "redis":
+		return OpenRedis(u)
+	default:
+		return nil, fmt.Errorf("unsupported queue scheme %q (use dir or redis)", u.Scheme)
+	}
+}
@@ -0,0 +1,242 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpool(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	q, err := Open("dir://" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, body := range []string{"10.0.0.1", "10.0.0.2"} {
+		if err := q.Push(ctx, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, ".partial"), []byte("ignored"), 0644)
+
+	first, err := q.Pop(ctx)
+	if err != nil || string(first.Body) != "10.0.0.1" {
+		t.Fatalf("first pop = %v, %v", first, err)
+	}
+	if err := first.Nack(); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := q.Pop(ctx)
+	if string(again.Body) != "10.0.0.1" {
+		t.Errorf("pop after nack = %q, want 10.0.0.1", again.Body)
+	}
+	again.Ack()
+
+	second, _ := q.Pop(ctx)
+	if string(second.Body) != "10.0.0.2" {
+		t.Errorf("second pop = %q, want 10.0.0.2", second.Body)
+	}
+	second.Ack()
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if msg, err := q.Pop(ctx); err == nil {
+		t.Errorf("pop on empty spool = %q, want timeout", msg.Body)
+	}
+}
+
+func TestSpoolRecovery(t *testing.T) {
+	pollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	ctx := context.Background()
+	popTimeout := func(q *Spool) (*Message, error) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		return q.Pop(ctx)
+	}
+
+	running, err := OpenSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"10.0.0.1", "10.0.0.2"} {
+		running.Push(ctx, []byte(body))
+	}
+	if msg, err := running.Pop(ctx); err != nil || string(msg.Body) != "10.0.0.1" {
+		t.Fatalf("pop = %v, %v", msg, err)
+	}
+
+	// A consumer that is still running keeps its claim.
+	other, err := OpenSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := other.Pop(ctx); string(msg.Body) != "10.0.0.2" {
+		t.Fatalf("pop beside a running consumer = %q, want 10.0.0.2", msg.Body)
+	}
+	if msg, err := popTimeout(other); err == nil {
+		t.Fatalf("claimed message handed out again: %q", msg.Body)
+	}
+
+	// Once the consumers are gone their claims go back into the spool, as
+	// do messages claimed by an older chainmap.
+	running.Close()
+	other.Close()
+	os.WriteFile(filepath.Join(dir, ".processing", "0-legacy.json"), []byte("10.0.0.3"), 0644)
+	q, err := OpenSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		msg, err := popTimeout(q)
+		if err != nil {
+			break
+		}
+		got = append(got, string(msg.Body))
+		msg.Ack()
+	}
+	if want := []string{"10.0.0.3", "10.0.0.1", "10.0.0.2"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("recovered %q, want %q", got, want)
+	}
+
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := os.ReadDir(filepath.Join(dir, ".processing")); len(left) != 0 {
+		t.Errorf(".processing/ holds %d entries after Close", len(left))
+	}
+}
+
+// fakeRedis serves RPUSH and BLPOP on in-memory lists.
+func fakeRedis(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	var mu sync.Mutex
+	lists := map[string][]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				for {
+					reply, err := readReply(br)
+					if err != nil {
+						return
+					}
+					var args []string
+					for _, a := range reply.([]interface{}) {
+						args = append(args, string(a.([]byte)))
+					}
+					mu.Lock()
+					switch args[0] {
+					case "RPUSH":
+						lists[args[1]] = append(lists[args[1]], args[2:]...)
+						conn.Write([]byte(":" + strconv.Itoa(len(lists[args[1]])) + "\r\n"))
+					case "LPUSH":
+						lists[args[1]] = append(args[2:], lists[args[1]]...)
+						conn.Write([]byte(":" + strconv.Itoa(len(lists[args[1]])) + "\r\n"))
+					case "BLPOP":
+						if l := lists[args[1]]; len(l) > 0 {
+							lists[args[1]] = l[1:]
+							conn.Write([]byte("*2\r\n$" + strconv.Itoa(len(args[1])) + "\r\n" + args[1] + "\r\n$" + strconv.Itoa(len(l[0])) + "\r\n" + l[0] + "\r\n"))
+						} else {
+							conn.Write([]byte("*-1\r\n"))
+						}
+					default:
+						conn.Write([]byte("-ERR unknown command\r\n"))
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRedis(t *testing.T) {
+	addr := fakeRedis(t)
+	q, err := Open("redis://" + addr + "?key=targets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	ctx := context.Background()
+
+	for _, body := range []string{"10.0.0.1", "scanme.example:22,80"} {
+		if err := q.Push(ctx, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, err := q.Pop(ctx)
+	if err != nil || string(first.Body) != "10.0.0.1" {
+		t.Fatalf("first pop = %v, %v", first, err)
+	}
+	if err := first.Nack(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"10.0.0.1", "scanme.example:22,80"} {
+		msg, err := q.Pop(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(msg.Body) != want {
+			t.Errorf("pop = %q, want %q", msg.Body, want)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := q.Pop(ctx); err == nil {
+		t.Error("pop with cancelled context succeeded")
+	}
+}
+
+func TestRedisAddr(t *testing.T) {
+	tests := map[string]string{
+		"localhost":      "localhost:6379",
+		"localhost:6380": "localhost:6380",
+		"10.0.0.1":       "10.0.0.1:6379",
+		"::1":            "[::1]:6379",
+		"[::1]":          "[::1]:6379",
+		"[::1]:6380":     "[::1]:6380",
+	}
+	for host, want := range tests {
+		if got := redisAddr(host); got != want {
+			t.Errorf("redisAddr(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	tests := []string{
+		"amqp://localhost/targets",
+		"redis://127.0.0.1:1",
+		"redis://127.0.0.1:1?key=targets",
+	}
+	for _, url := range tests {
+		if q, err := Open(url); err == nil {
+			q.Close()
+			t.Errorf("Open(%q) succeeded", url)
+		}
+	}
+}
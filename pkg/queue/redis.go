@@ -0,0 +1,222 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// popWait is how long one BLPOP blocks before ctx is checked again.
+const popWait = time.Second
+
+// Redis is a queue backed by a Redis list. Producers append with RPUSH and
+// consumers take from the head with BLPOP. It speaks plain RESP, so any
+// server implementing those commands works. A popped message is gone from
+// the list; Nack pushes it back to the head, but a consumer that dies
+// without calling it loses the message.
+type Redis struct {
+	addr     string
+	password string
+	db       int
+	key      string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// OpenRedis connects to the list named by the key query parameter of u.
+func OpenRedis(u *url.URL) (*Redis, error) {
+	r := &Redis{addr: redisAddr(u.Host), key: u.Query().Get("key")}
+	if r.key == "" {
+		return nil, fmt.Errorf("redis queue URL needs a ?key= list name")
+	}
+	if pass, ok := u.User.Password(); ok {
+		r.password = pass
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", db)
+		}
+		r.db = n
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// redisAddr adds the default port to host when it has none. Bare IPv6
+// addresses such as "::1" and "[::1]" get brackets.
+func redisAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), "6379")
+}
+
+// Pop takes the first element of the list.
+func (r *Redis) Pop(ctx context.Context) (*Message, error) {
+	wait := strconv.Itoa(int(popWait / time.Second))
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reply, err := r.do(popWait+5*time.Second, "BLPOP", r.key, wait)
+		if err != nil {
+			return nil, err
+		}
+		// BLPOP answers with [key, value], or nil on timeout.
+		if items, ok := reply.([]interface{}); ok && len(items) == 2 {
+			if body, ok := items[1].([]byte); ok {
+				return &Message{
+					Body: body,
+					nack: func() error {
+						_, err := r.do(10*time.Second, "LPUSH", r.key, string(body))
+						return err
+					},
+				}, nil
+			}
+		}
+	}
+}
+
+// Push appends body to the list.
+func (r *Redis) Push(ctx context.Context, body []byte) error {
+	_, err := r.do(10*time.Second, "RPUSH", r.key, string(body))
+	return err
+}
+
+// Close closes the connection.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+// do sends one command and reads its reply, reconnecting first if an
+// earlier command broke the connection.
+func (r *Redis) do(timeout time.Duration, args ...string) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(); err != nil {
+			return nil, err
+		}
+	}
+	r.conn.SetDeadline(time.Now().Add(timeout))
+	reply, err := r.roundTrip(args...)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		r.conn.Close()
+		r.conn = nil
+	}
+	return reply, err
+}
+
+// connect dials the server and authenticates. The caller holds r.mu.
+func (r *Redis) connect() error {
+	conn, err := net.DialTimeout("tcp", r.addr, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to connect to redis at %s: %w", r.addr, err)
+	}
+	r.conn, r.reader = conn, bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	if r.password != "" {
+		if _, err := r.roundTrip("AUTH", r.password); err != nil {
+			conn.Close()
+			r.conn = nil
+			return fmt.Errorf("redis authentication failed: %w", err)
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.roundTrip("SELECT", strconv.Itoa(r.db)); err != nil {
+			conn.Close()
+			r.conn = nil
+			return fmt.Errorf("failed to select redis database %d: %w", r.db, err)
+		}
+	}
+	return nil
+}
+
+func (r *Redis) roundTrip(args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(r.reader)
+}
+
+// redisError is an error reply from the server. The connection stays usable.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readReply parses one RESP reply. Bulk strings are returned as []byte,
+// arrays as []interface{} and nil replies as nil.
+func readReply(br *bufio.Reader) (interface{}, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(br); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
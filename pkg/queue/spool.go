@@ -0,0 +1,198 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pollInterval is how often an empty spool directory is checked again.
+var pollInterval = time.Second
+
+// Spool is a queue backed by a directory. Every regular file in it is one
+// message; files are consumed in name order. A consumer claims a file by
+// moving it into its own directory below .processing/, so several
+// processes can share a spool. Files starting with "." are ignored, which
+// lets producers write to a temporary name and rename it into place.
+type Spool struct {
+	dir        string
+	processing string
+	// lock is held on the .lock file of processing while the spool is
+	// open, so other consumers can tell it is still in use.
+	lock *os.File
+}
+
+// lockName is the lock file of a claim directory.
+const lockName = ".lock"
+
+// OpenSpool creates dir and its .processing/ directory if needed. Messages
+// claimed by consumers that stopped without acknowledging them are moved
+// back into the spool first.
+func OpenSpool(dir string) (*Spool, error) {
+	root := filepath.Join(dir, ".processing")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	if err := recoverClaims(dir, root); err != nil {
+		return nil, fmt.Errorf("failed to recover claimed messages: %w", err)
+	}
+
+	// The claim directory is locked under a dot name, which recovery
+	// skips, before it is renamed into place.
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	id := fmt.Sprintf("%d-%s", os.Getpid(), hex.EncodeToString(b))
+	tmp := filepath.Join(root, "."+id)
+	if err := os.Mkdir(tmp, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	lock, err := lockFile(filepath.Join(tmp, lockName))
+	if err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	s := &Spool{dir: dir, processing: filepath.Join(root, id), lock: lock}
+	if err := os.Rename(tmp, s.processing); err != nil {
+		s.lock.Close()
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	return s, nil
+}
+
+// recoverClaims moves the messages below root whose consumer is gone back
+// into dir.
+func recoverClaims(dir, root string) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		path := filepath.Join(root, e.Name())
+		switch {
+		case strings.HasPrefix(e.Name(), "."):
+			continue
+		case e.Type().IsRegular():
+			// Claimed directly in .processing/ by an older chainmap.
+			if err := os.Rename(path, filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		case e.IsDir():
+			if err := recoverClaimDir(dir, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recoverClaimDir empties and removes the claim directory at path unless
+// its consumer still holds the lock.
+func recoverClaimDir(dir, path string) error {
+	lock, err := lockFile(filepath.Join(path, lockName))
+	if err != nil {
+		return nil
+	}
+	defer lock.Close()
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+			if err := os.Rename(filepath.Join(path, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return os.RemoveAll(path)
+}
+
+// Pop claims the oldest file in the spool.
+func (s *Spool) Pop(ctx context.Context) (*Message, error) {
+	for {
+		msg, err := s.claim()
+		if msg != nil || err != nil {
+			return msg, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (s *Spool) claim() (*Message, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	for _, e := range entries {
+		if !e.Type().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		src := filepath.Join(s.dir, e.Name())
+		claimed := filepath.Join(s.processing, e.Name())
+		// Another consumer won the race for this file.
+		if err := os.Rename(src, claimed); err != nil {
+			continue
+		}
+		body, err := os.ReadFile(claimed)
+		if err != nil {
+			os.Rename(claimed, src)
+			return nil, err
+		}
+		return &Message{
+			Body: body,
+			ack:  func() error { return os.Remove(claimed) },
+			nack: func() error { return os.Rename(claimed, src) },
+		}, nil
+	}
+	return nil, nil
+}
+
+// Push writes body to a new file named after the current time.
+func (s *Spool) Push(ctx context.Context, body []byte) error {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%020d-%s.json", time.Now().UnixNano(), hex.EncodeToString(b))
+
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, body, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+// Close releases the claim directory. Messages still claimed stay in it
+// until the next OpenSpool moves them back.
+func (s *Spool) Close() error {
+	if s.lock != nil {
+		defer s.lock.Close()
+	}
+	entries, err := os.ReadDir(s.processing)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() != lockName {
+			return nil
+		}
+	}
+	return os.RemoveAll(s.processing)
+}
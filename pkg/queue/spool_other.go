@@ -0,0 +1,14 @@
+//go:build !unix
+
+package queue
+
+import (
+	"errors"
+	"os"
+)
+
+// Without flock a spool cannot tell whether the consumer of a claim
+// directory is still running, so claimed messages are never recovered.
+func lockFile(path string) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package queue
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on path without waiting for it. The
+// lock is held until the file is closed or the process exits.
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,211 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lair-framework/go-nmap"
+	"github.com/xuri/excelize/v2"
+)
+
+// DefaultColumns is the column set and order used when none is configured.
+var DefaultColumns = []string{
+	"ip", "hostnames", "port", "protocol", "state", "reason",
+	"service", "product", "version", "extrainfo", "cpe", "scripts",
+}
+
+// maxCellLength keeps script summaries under the spreadsheet cell limit. It
+// counts bytes, which are never fewer than the characters a spreadsheet
+// counts.
+const maxCellLength = 32000
+
+type columnFunc func(host nmap.Host, port *nmap.Port) string
+
+var columns = map[string]columnFunc{
+	"ip": func(h nmap.Host, _ *nmap.Port) string {
+		if len(h.Addresses) > 0 {
+			return h.Addresses[0].Addr
+		}
+		return ""
+	},
+	"hostnames": func(h nmap.Host, _ *nmap.Port) string {
+		names := make([]string, 0, len(h.Hostnames))
+		for _, hn := range h.Hostnames {
+			names = append(names, hn.Name)
+		}
+		return strings.Join(names, " ")
+	},
+	"port":      portColumn(func(p *nmap.Port) string { return strconv.Itoa(p.PortId) }),
+	"protocol":  portColumn(func(p *nmap.Port) string { return p.Protocol }),
+	"state":     portColumn(func(p *nmap.Port) string { return p.State.State }),
+	"reason":    portColumn(func(p *nmap.Port) string { return p.State.Reason }),
+	"service":   portColumn(func(p *nmap.Port) string { return p.Service.Name }),
+	"product":   portColumn(func(p *nmap.Port) string { return p.Service.Product }),
+	"version":   portColumn(func(p *nmap.Port) string { return p.Service.Version }),
+	"extrainfo": portColumn(func(p *nmap.Port) string { return p.Service.ExtraInfo }),
+	"cpe": portColumn(func(p *nmap.Port) string {
+		cpes := make([]string, 0, len(p.Service.CPEs))
+		for _, c := range p.Service.CPEs {
+			cpes = append(cpes, string(c))
+		}
+		return strings.Join(cpes, " ")
+	}),
+	"scripts": portColumn(func(p *nmap.Port) string {
+		parts := make([]string, 0, len(p.Scripts))
+		for _, s := range p.Scripts {
+			parts = append(parts, fmt.Sprintf("%s: %s", s.Id, strings.Join(strings.Fields(s.Output), " ")))
+		}
+		summary := strings.Join(parts, " | ")
+		return truncateBytes(summary, maxCellLength)
+	}),
+}
+
+// truncateBytes cuts s to at most n bytes without splitting a rune.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func portColumn(fn func(p *nmap.Port) string) columnFunc {
+	return func(_ nmap.Host, p *nmap.Port) string {
+		if p == nil {
+			return ""
+		}
+		return fn(p)
+	}
+}
+
+func init() {
+	columnOptions := map[string]string{"columns": "comma separated columns and their order"}
+	Register(&Format{Name: "csv", Title: "CSV", Extensions: []string{".csv"}, Options: columnOptions, New: columnsWriter(WriteCSV)})
+	Register(&Format{Name: "xlsx", Title: "XLSX", Extensions: []string{".xlsx"}, Options: columnOptions, New: columnsWriter(WriteXLSX)})
+}
+
+// columnsWriter builds a writer for a flat table format from its columns
+// option.
+func columnsWriter(write func(*nmap.NmapRun, []string, string) error) func(map[string]string) (Writer, error) {
+	return func(opts map[string]string) (Writer, error) {
+		cols, err := ParseColumns(opts["columns"])
+		if err != nil {
+			return nil, err
+		}
+		return WriterFunc(func(res *Results, path string) error {
+			return write(res.Run, cols, path)
+		}), nil
+	}
+}
+
+// ParseColumns turns a comma separated column list into a validated
+// selection. An empty list selects DefaultColumns.
+func ParseColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return DefaultColumns, nil
+	}
+	var selected []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(DefaultColumns, ","))
+		}
+		selected = append(selected, name)
+	}
+	return selected, nil
+}
+
+// FlatRows returns one row per host and port, preceded by a header row.
+// Hosts without ports still get a single row.
+func FlatRows(run *nmap.NmapRun, cols []string) [][]string {
+	rows := [][]string{cols}
+	for _, host := range run.Hosts {
+		if len(host.Ports) == 0 {
+			rows = append(rows, flatRow(host, nil, cols))
+			continue
+		}
+		for i := range host.Ports {
+			rows = append(rows, flatRow(host, &host.Ports[i], cols))
+		}
+	}
+	return rows
+}
+
+func flatRow(host nmap.Host, port *nmap.Port, cols []string) []string {
+	row := make([]string, len(cols))
+	for i, c := range cols {
+		row[i] = columns[c](host, port)
+	}
+	return row
+}
+
+// WriteCSV writes the flat host/port table as CSV.
+func WriteCSV(run *nmap.NmapRun, cols []string, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rows := FlatRows(run, cols)
+	for _, row := range rows {
+		for i, v := range row {
+			row[i] = csvSafe(v)
+		}
+	}
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// csvSafe keeps spreadsheet applications from evaluating scanned content,
+// such as script output, as a formula. Leading tabs and carriage returns are
+// escaped too, since some applications skip them before looking for one.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// WriteXLSX writes the flat host/port table as an Excel workbook.
+func WriteXLSX(run *nmap.NmapRun, cols []string, path string) error {
+	wb := excelize.NewFile()
+	defer wb.Close()
+
+	const sheet = "Results"
+	if err := wb.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	for r, row := range FlatRows(run, cols) {
+		cells := make([]interface{}, len(row))
+		for i, v := range row {
+			cells[i] = v
+		}
+		cell, err := excelize.CoordinatesToCellName(1, r+1)
+		if err != nil {
+			return err
+		}
+		if err := wb.SetSheetRow(sheet, cell, &cells); err != nil {
+			return err
+		}
+	}
+
+	if err := wb.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}); err != nil {
+		return err
+	}
+	return wb.SaveAs(path)
+}
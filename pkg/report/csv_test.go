@@ -0,0 +1,127 @@
+package report
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+func csvTestRun() *nmap.NmapRun {
+	return &nmap.NmapRun{Hosts: []nmap.Host{
+		{
+			Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+			Hostnames: []nmap.Hostname{{Name: "a.example"}, {Name: "b.example"}},
+			Ports: []nmap.Port{
+				{PortId: 22, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "ssh", Product: "OpenSSH"}},
+				{PortId: 80, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "http", Product: "=HYPERLINK(\"x\")"},
+					Scripts: []nmap.Script{{Id: "http-title", Output: "\n  Site\n  title "}}},
+			},
+		},
+		{
+			Addresses: []nmap.Address{{Addr: "10.0.0.2"}},
+		},
+	}}
+}
+
+func TestParseColumns(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{"", DefaultColumns, false},
+		{"  ", DefaultColumns, false},
+		{"port,ip", []string{"port", "ip"}, false},
+		{" IP , Service,,", []string{"ip", "service"}, false},
+		{"ip,nope", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseColumns(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseColumns() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlatRows(t *testing.T) {
+	got := FlatRows(csvTestRun(), []string{"ip", "hostnames", "port", "service", "scripts"})
+	want := [][]string{
+		{"ip", "hostnames", "port", "service", "scripts"},
+		{"10.0.0.1", "a.example b.example", "22", "ssh", ""},
+		{"10.0.0.1", "a.example b.example", "80", "http", "http-title: Site title"},
+		{"10.0.0.2", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlatRows() = %q, want %q", got, want)
+	}
+}
+
+func TestScriptsColumnLimit(t *testing.T) {
+	// A multi-byte rune straddles the limit.
+	output := strings.Repeat("a", maxCellLength-len("big: ")-1) + "é tail"
+	port := &nmap.Port{Scripts: []nmap.Script{{Id: "big", Output: output}}}
+	got := columns["scripts"](nmap.Host{}, port)
+	if len(got) > maxCellLength || !utf8.ValidString(got) {
+		t.Errorf("scripts column is %d bytes, valid UTF-8 %v", len(got), utf8.ValidString(got))
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"OpenSSH", "OpenSSH"},
+		{"=1+1", "'=1+1"},
+		{"+1", "'+1"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "'\r=1+1"},
+		{"a=1", "a=1"},
+	}
+	for _, tt := range tests {
+		if got := csvSafe(tt.in); got != tt.want {
+			t.Errorf("csvSafe(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.csv")
+	if err := WriteCSV(csvTestRun(), []string{"ip", "port", "product"}, path); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"ip", "port", "product"},
+		{"10.0.0.1", "22", "OpenSSH"},
+		{"10.0.0.1", "80", "'=HYPERLINK(\"x\")"},
+		{"10.0.0.2", "", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteCSV() rows = %q, want %q", got, want)
+	}
+}
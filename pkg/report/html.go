@@ -0,0 +1,131 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+// vulnMarker is emitted by core.DefaultXSLT where the vulnerability section goes.
+const vulnMarker = "<!--chainmap:vulnerabilities-->"
+
+var vulnTemplate = template.Must(template.New("vulns").Funcs(template.FuncMap{"badge": severityBadge}).Parse(`
+<div class="host-card">
+  <div class="host-header">
+    <div class="host-title">Vulnerabilities</div>
+    <div class="badge badge-danger">{{len .}} CVEs</div>
+  </div>
+  <table>
+    <thead>
+      <tr><th>Severity</th><th>CVE</th><th>Target</th><th>Service</th><th>Source</th></tr>
+    </thead>
+    <tbody>
+      {{range .}}
+      <tr>
+        <td><span class="badge {{badge .Severity}}">{{.Severity}}{{if gt .CVSS 0.0}} {{printf "%.1f" .CVSS}}{{end}}</span></td>
+        <td>{{if .URL}}<a href="{{.URL}}">{{.ID}}</a>{{else}}{{.ID}}{{end}}{{if .Exploit}} <span class="badge badge-danger">exploit</span>{{end}}</td>
+        <td>{{.Host}}:{{.Port}}/{{.Protocol}}</td>
+        <td>{{.Product}} {{.Version}}</td>
+        <td>{{.Source}}</td>
+      </tr>
+      {{end}}
+    </tbody>
+  </table>
+</div>
+`))
+
+func init() {
+	Register(&Format{
+		Name:       "html",
+		Title:      "HTML",
+		Extensions: []string{".html", ".htm"},
+		Options:    map[string]string{"stylesheet": "XSLT stylesheet replacing the embedded one"},
+		New: func(opts map[string]string) (Writer, error) {
+			stylesheet := opts["stylesheet"]
+			if stylesheet != "" {
+				if _, err := os.Stat(stylesheet); err != nil {
+					return nil, err
+				}
+			}
+			return WriterFunc(func(res *Results, path string) error {
+				return WriteHTML(res.Run, res.Vulns, stylesheet, path)
+			}), nil
+		},
+	})
+}
+
+// WriteHTML renders run to path with xsltproc, using the embedded
+// core.DefaultXSLT unless stylesheet names another one, and adds the
+// vulnerability table.
+func WriteHTML(run *nmap.NmapRun, vulns []core.Vulnerability, stylesheet, path string) error {
+	if _, err := exec.LookPath("xsltproc"); err != nil {
+		return fmt.Errorf("xsltproc is not installed")
+	}
+	dir, err := os.MkdirTemp("", "chainmap-html-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	xmlPath := filepath.Join(dir, "results.xml")
+	if err := core.WriteXML(run, xmlPath); err != nil {
+		return err
+	}
+	embedded := stylesheet == ""
+	if embedded {
+		stylesheet = filepath.Join(dir, "nmap.xsl")
+		if err := os.WriteFile(stylesheet, []byte(core.DefaultXSLT), 0644); err != nil {
+			return err
+		}
+	}
+
+	if out, err := exec.Command("xsltproc", "-o", path, stylesheet, xmlPath).CombinedOutput(); err != nil {
+		return fmt.Errorf("xsltproc: %w: %s", err, bytes.TrimSpace(out))
+	}
+	// A custom stylesheet has no marker for the table, so it is left as is.
+	if embedded {
+		return InjectVulnerabilities(path, vulns)
+	}
+	return nil
+}
+
+// InjectVulnerabilities adds the vulnerability table to an HTML report
+// generated from core.DefaultXSLT.
+func InjectVulnerabilities(htmlPath string, vulns []core.Vulnerability) error {
+	if len(vulns) == 0 {
+		return nil
+	}
+
+	page, err := os.ReadFile(htmlPath)
+	if err != nil {
+		return err
+	}
+	if !bytes.Contains(page, []byte(vulnMarker)) {
+		return fmt.Errorf("vulnerability marker not found in %s", htmlPath)
+	}
+
+	var section bytes.Buffer
+	if err := vulnTemplate.Execute(&section, vulns); err != nil {
+		return err
+	}
+
+	page = bytes.Replace(page, []byte(vulnMarker), section.Bytes(), 1)
+	return os.WriteFile(htmlPath, page, 0644)
+}
+
+func severityBadge(severity string) string {
+	switch severity {
+	case "critical", "high":
+		return "badge-danger"
+	case "medium":
+		return "badge-warning"
+	default:
+		return "badge-success"
+	}
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"html/template"
+	"os"
+	"time"
+)
+
+// Index is the data of the index.html page of an -output-dir tree. All
+// paths are relative to the tree.
+type Index struct {
+	Generated time.Time
+	Command   string
+	Files     []string
+	Hosts     []IndexHost
+}
+
+// IndexHost is the folder of one job in the tree.
+type IndexHost struct {
+	Target    string
+	Status    string
+	OpenPorts int
+	Dir       string
+	Files     []string
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Chainmap Scan Results</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2rem; color: #1f2933; }
+  h1 { margin-bottom: 0.2rem; }
+  .meta { color: #616e7c; margin-bottom: 1.5rem; }
+  code { background: #f0f4f8; padding: 0.1rem 0.3rem; border-radius: 3px; }
+  table { border-collapse: collapse; width: 100%; }
+  th, td { text-align: left; padding: 0.4rem 0.8rem; border-bottom: 1px solid #e4e7eb; }
+  th { background: #f5f7fa; }
+  a { color: #2680c2; text-decoration: none; margin-right: 0.8rem; }
+  a:hover { text-decoration: underline; }
+  .status-ok { color: #2f8132; }
+  .status-failed, .status-timeout { color: #ba2525; }
+</style>
+</head>
+<body>
+<h1>Chainmap Scan Results</h1>
+<div class="meta">Generated {{ .Generated.Format "2006-01-02 15:04 MST" }}{{ if .Command }} by <code>{{ .Command }}</code>{{ end }}</div>
+
+<h2>Results</h2>
+<p>{{ range .Files }}<a href="{{ . }}">{{ . }}</a>{{ else }}No merged results were written.{{ end }}</p>
+
+<h2>Hosts ({{ len .Hosts }})</h2>
+<table>
+  <thead>
+    <tr><th>Target</th><th>Status</th><th>Open ports</th><th>Files</th></tr>
+  </thead>
+  <tbody>
+    {{- range .Hosts }}
+    <tr>
+      <td>{{ .Target }}</td>
+      <td class="status-{{ .Status }}">{{ .Status }}</td>
+      <td>{{ .OpenPorts }}</td>
+      <td>{{ $dir := .Dir }}{{ range .Files }}<a href="{{ $dir }}/{{ . }}">{{ . }}</a>{{ end }}</td>
+    </tr>
+    {{- end }}
+  </tbody>
+</table>
+</body>
+</html>
+`))
+
+// WriteIndex writes the index.html page of an -output-dir tree.
+func WriteIndex(index *Index, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := indexTemplate.Execute(f, index); err != nil {
+		return err
+	}
+	return f.Close()
+}
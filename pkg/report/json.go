@@ -0,0 +1,56 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+func init() {
+	Register(&Format{
+		Name:       "json",
+		Title:      "JSON",
+		Extensions: []string{".json"},
+		Options:    map[string]string{"indent": "indent the document (default true)"},
+		New: func(opts map[string]string) (Writer, error) {
+			indent := true
+			if v, ok := opts["indent"]; ok {
+				var err error
+				if indent, err = strconv.ParseBool(v); err != nil {
+					return nil, fmt.Errorf("invalid indent %q", v)
+				}
+			}
+			return WriterFunc(func(res *Results, path string) error {
+				return writeJSON(JSONReport{Scan: res.Run, Vulnerabilities: res.Vulns, Downgraded: res.Downgraded}, indent, path)
+			}), nil
+		},
+	})
+}
+
+// JSONReport is the document written for .json outputs.
+type JSONReport struct {
+	Scan            *nmap.NmapRun        `json:"scan"`
+	Vulnerabilities []core.Vulnerability `json:"vulnerabilities"`
+	Downgraded      []core.FlagChange    `json:"downgraded,omitempty"`
+}
+
+func writeJSON(doc JSONReport, indent bool, path string) error {
+	if doc.Vulnerabilities == nil {
+		doc.Vulnerabilities = []core.Vulnerability{}
+	}
+	var data []byte
+	var err error
+	if indent {
+		data, err = json.MarshalIndent(doc, "", "  ")
+	} else {
+		data, err = json.Marshal(doc)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
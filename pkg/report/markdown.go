@@ -0,0 +1,261 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+// MarkdownReport is the data passed to the Markdown template. Custom
+// templates given with -md-template receive the same structure.
+type MarkdownReport struct {
+	Generated       time.Time
+	HostsTotal      int
+	HostsUp         int
+	OpenPorts       int
+	Services        []ServiceCount
+	Severities      []ServiceCount
+	Hosts           []MarkdownHost
+	Vulnerabilities []core.Vulnerability
+	Commands        []string
+	// Downgraded lists the nmap flags rewritten by -auto-downgrade.
+	Downgraded []core.FlagChange
+}
+
+// ServiceCount is a name with the number of times it was seen.
+type ServiceCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// MarkdownHost is one host section of the report.
+type MarkdownHost struct {
+	Address   string
+	Hostnames []string
+	Status    string
+	Ports     []nmap.Port
+	Scripts   []ScriptFinding
+}
+
+// ScriptFinding is the output of one NSE script on a host or port.
+type ScriptFinding struct {
+	Port   string
+	ID     string
+	Output string
+}
+
+const defaultMarkdownTemplate = `# Chainmap Scan Report
+
+_Generated {{ .Generated.Format "2006-01-02 15:04 MST" }}_
+
+## Executive Summary
+
+| Metric | Value |
+| --- | --- |
+| Hosts scanned | {{ .HostsTotal }} |
+| Hosts up | {{ .HostsUp }} |
+| Open ports | {{ .OpenPorts }} |
+| Vulnerabilities | {{ len .Vulnerabilities }} |
+{{- range .Severities }}
+| {{ .Name }} | {{ .Count }} |
+{{- end }}
+{{ if .Services }}
+**Top services:** {{ range $i, $s := .Services }}{{ if $i }}, {{ end }}{{ $s.Name }} ({{ $s.Count }}){{ end }}
+{{ end }}
+{{- if .Vulnerabilities }}
+## Vulnerabilities
+
+| Severity | CVSS | CVE | Target | Service | Exploit |
+| --- | --- | --- | --- | --- | --- |
+{{- range .Vulnerabilities }}
+| {{ .Severity }} | {{ printf "%.1f" .CVSS }} | {{ if .URL }}[{{ .ID }}]({{ .URL }}){{ else }}{{ .ID }}{{ end }} | {{ .Host }}:{{ .Port }}/{{ .Protocol }} | {{ cell .Product }} {{ cell .Version }} | {{ if .Exploit }}yes{{ end }} |
+{{- end }}
+{{ end }}
+## Hosts
+{{ range .Hosts }}
+### {{ .Address }}{{ if .Hostnames }} ({{ join .Hostnames ", " }}){{ end }}
+
+Status: **{{ .Status }}**
+{{ if .Ports }}
+| Port | State | Service | Product | Version | Extra |
+| --- | --- | --- | --- | --- | --- |
+{{- range .Ports }}
+| {{ .PortId }}/{{ .Protocol }} | {{ .State.State }} | {{ cell .Service.Name }} | {{ cell .Service.Product }} | {{ cell .Service.Version }} | {{ cell .Service.ExtraInfo }} |
+{{- end }}
+{{ else }}
+No ports reported.
+{{ end }}
+{{- range .Scripts }}
+**{{ .ID }}**{{ if .Port }} on {{ .Port }}{{ end }}
+
+{{ fence .Output }}
+{{ end }}
+{{- end }}
+{{- if .Downgraded }}
+## Flag Downgrades
+
+nmap had no raw socket access, so -auto-downgrade rewrote these flags:
+
+| Flag | Replacement | Reason |
+| --- | --- | --- |
+{{- range .Downgraded }}
+| {{ cell .From }} | {{ if .To }}{{ cell .To }}{{ else }}dropped{{ end }} | {{ .Reason }} |
+{{- end }}
+{{ end }}
+{{- if .Commands }}
+## Appendix: Nmap Commands
+
+{{ fence (join .Commands "\n") }}
+{{- end }}
+`
+
+// NewMarkdownReport collects the report data for run.
+func NewMarkdownReport(run *nmap.NmapRun, vulns []core.Vulnerability, commands []string) *MarkdownReport {
+	rep := &MarkdownReport{
+		Generated:       time.Now(),
+		HostsTotal:      len(run.Hosts),
+		Vulnerabilities: vulns,
+		Commands:        commands,
+	}
+
+	services := make(map[string]int)
+	for _, host := range run.Hosts {
+		mh := MarkdownHost{Status: host.Status.State, Ports: host.Ports}
+		if len(host.Addresses) > 0 {
+			mh.Address = host.Addresses[0].Addr
+		}
+		for _, hn := range host.Hostnames {
+			mh.Hostnames = append(mh.Hostnames, hn.Name)
+		}
+		if host.Status.State == "up" {
+			rep.HostsUp++
+		}
+
+		for _, port := range host.Ports {
+			if port.State.State == "open" {
+				rep.OpenPorts++
+				if port.Service.Name != "" {
+					services[port.Service.Name]++
+				}
+			}
+			for _, s := range port.Scripts {
+				mh.Scripts = append(mh.Scripts, ScriptFinding{
+					Port:   fmt.Sprintf("%d/%s", port.PortId, port.Protocol),
+					ID:     s.Id,
+					Output: strings.TrimSpace(s.Output),
+				})
+			}
+		}
+		for _, s := range host.HostScripts {
+			mh.Scripts = append(mh.Scripts, ScriptFinding{ID: s.Id, Output: strings.TrimSpace(s.Output)})
+		}
+		rep.Hosts = append(rep.Hosts, mh)
+	}
+
+	rep.Services = sortedCounts(services)
+	if len(rep.Services) > 10 {
+		rep.Services = rep.Services[:10]
+	}
+
+	severities := make(map[string]int)
+	for _, v := range vulns {
+		severities[v.Severity]++
+	}
+	for _, sev := range []string{"critical", "high", "medium", "low", "unknown"} {
+		if severities[sev] > 0 {
+			rep.Severities = append(rep.Severities, ServiceCount{Name: strings.ToUpper(sev[:1]) + sev[1:], Count: severities[sev]})
+		}
+	}
+	return rep
+}
+
+func init() {
+	Register(&Format{
+		Name:       "md",
+		Title:      "Markdown",
+		Extensions: []string{".md", ".markdown"},
+		Options:    map[string]string{"template": "Go text/template file replacing the built-in layout"},
+		New: func(opts map[string]string) (Writer, error) {
+			tmpl, err := loadMarkdownTemplate(opts["template"])
+			if err != nil {
+				return nil, err
+			}
+			return WriterFunc(func(res *Results, path string) error {
+				rep := NewMarkdownReport(res.Run, res.Vulns, res.Commands)
+				rep.Downgraded = res.Downgraded
+				return executeMarkdown(tmpl, rep, path)
+			}), nil
+		},
+	})
+}
+
+func loadMarkdownTemplate(templatePath string) (*template.Template, error) {
+	text := defaultMarkdownTemplate
+	if templatePath != "" {
+		data, err := os.ReadFile(templatePath)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New("markdown").Funcs(markdownFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid markdown template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func executeMarkdown(tmpl *template.Template, rep *MarkdownReport, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, rep); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+var markdownFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"cell":  markdownCell,
+	"fence": markdownFence,
+}
+
+// markdownCell escapes a value for use inside a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// markdownFence wraps s in a code fence longer than any backtick run inside it.
+func markdownFence(s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + "\n" + s + "\n" + fence
+}
+
+func sortedCounts(counts map[string]int) []ServiceCount {
+	list := make([]ServiceCount, 0, len(counts))
+	for name, n := range counts {
+		list = append(list, ServiceCount{Name: name, Count: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+func TestMarkdownCell(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"OpenSSH", "OpenSSH"},
+		{"a|b", `a\|b`},
+		{"multi\nline\r\n  value", "multi line value"},
+		{"  padded\t", "padded"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := markdownCell(tt.in); got != tt.want {
+			t.Errorf("markdownCell(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownFence(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain", "```\nplain\n```"},
+		{"has `code`", "```\nhas `code`\n```"},
+		{"has ``` fence", "````\nhas ``` fence\n````"},
+		{"has ```` longer", "`````\nhas ```` longer\n`````"},
+	}
+	for _, tt := range tests {
+		if got := markdownFence(tt.in); got != tt.want {
+			t.Errorf("markdownFence(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func markdownTestRun() *nmap.NmapRun {
+	return &nmap.NmapRun{Hosts: []nmap.Host{
+		{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+			Hostnames: []nmap.Hostname{{Name: "web.example"}},
+			Ports: []nmap.Port{
+				{PortId: 22, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh"}},
+				{PortId: 80, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "http", Product: "Apache|httpd"},
+					Scripts: []nmap.Script{{Id: "http-title", Output: "\n  Index ``` page\n"}}},
+				{PortId: 8080, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "http"}},
+				{PortId: 443, Protocol: "tcp", State: nmap.State{State: "closed"}, Service: nmap.Service{Name: "https"}},
+			},
+			HostScripts: []nmap.Script{{Id: "smb-os-discovery", Output: "Windows"}},
+		},
+		{
+			Status:    nmap.Status{State: "down"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.2"}},
+		},
+	}}
+}
+
+func TestNewMarkdownReport(t *testing.T) {
+	vulns := []core.Vulnerability{
+		{ID: "CVE-1", Severity: "high"},
+		{ID: "CVE-2", Severity: "critical"},
+		{ID: "CVE-3", Severity: "high"},
+	}
+	rep := NewMarkdownReport(markdownTestRun(), vulns, []string{"nmap -sV 10.0.0.1"})
+
+	if rep.HostsTotal != 2 || rep.HostsUp != 1 || rep.OpenPorts != 3 {
+		t.Errorf("counts = %d hosts, %d up, %d open ports", rep.HostsTotal, rep.HostsUp, rep.OpenPorts)
+	}
+	wantServices := []ServiceCount{{"http", 2}, {"ssh", 1}}
+	if !reflect.DeepEqual(rep.Services, wantServices) {
+		t.Errorf("Services = %v, want %v", rep.Services, wantServices)
+	}
+	wantSeverities := []ServiceCount{{"Critical", 1}, {"High", 2}}
+	if !reflect.DeepEqual(rep.Severities, wantSeverities) {
+		t.Errorf("Severities = %v, want %v", rep.Severities, wantSeverities)
+	}
+
+	host := rep.Hosts[0]
+	if host.Address != "10.0.0.1" || !reflect.DeepEqual(host.Hostnames, []string{"web.example"}) || host.Status != "up" {
+		t.Errorf("host = %+v", host)
+	}
+	wantScripts := []ScriptFinding{
+		{Port: "80/tcp", ID: "http-title", Output: "Index ``` page"},
+		{ID: "smb-os-discovery", Output: "Windows"},
+	}
+	if !reflect.DeepEqual(host.Scripts, wantScripts) {
+		t.Errorf("Scripts = %+v, want %+v", host.Scripts, wantScripts)
+	}
+}
+
+func TestMarkdownOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.md")
+	out, err := ParseOutput(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &Results{Run: markdownTestRun(), Commands: []string{"nmap -sV 10.0.0.1"}}
+	if err := out.Write(res); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"| Hosts scanned | 2 |",
+		"### 10.0.0.1 (web.example)",
+		`| 80/tcp | open | http | Apache\|httpd |  |  |`,
+		"````\nIndex ``` page\n````",
+		"No ports reported.",
+		"## Appendix: Nmap Commands\n\n```\nnmap -sV 10.0.0.1\n```",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("report is missing %q:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "## Vulnerabilities") {
+		t.Error("report has a vulnerability section without vulnerabilities")
+	}
+}
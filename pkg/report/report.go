@@ -2,55 +2,44 @@ package report
 
 import (
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/ihsanlearn/chainmap/core"
-	"github.com/ihsanlearn/chainmap/logger"
 )
 
-func GenerateSummary(outputFile string) {
-	if _, err := os.Stat(outputFile); os.IsNotExist(err) {
-		logger.Info("No result file found at %s", outputFile)
+func printVulnerabilities(vulns []core.Vulnerability) {
+	if len(vulns) == 0 {
 		return
 	}
 
-	files := []string{outputFile}
-
-	green := color.New(color.FgGreen).SprintfFunc()
-	yellow := color.New(color.FgYellow).SprintfFunc()
 	bold := color.New(color.Bold).SprintfFunc()
+	fmt.Println(bold("\n--- Vulnerabilities (%d) ---", len(vulns)))
 
-	fmt.Println(bold("\n--- Scan Summary ---"))
-
-	for _, file := range files {
-		nmapRun, err := core.ParseXML(file)
-		if err != nil {
-			logger.Error("Failed to parse %s: %s", file, err)
-			continue
+	for _, v := range vulns {
+		score := "n/a"
+		if v.CVSS > 0 {
+			score = fmt.Sprintf("%.1f", v.CVSS)
 		}
-
-		for _, host := range nmapRun.Hosts {
-			ip := ""
-			if len(host.Addresses) > 0 {
-				ip = host.Addresses[0].Addr
-			}
-
-			for _, port := range host.Ports {
-				if port.State.State == "open" {
-					service := port.Service.Name
-					product := port.Service.Product
-					version := port.Service.Version
-
-					fullService := service
-					if product != "" {
-						fullService += fmt.Sprintf(" (%s %s)", product, version)
-					}
-
-					fmt.Printf("%s %s -> %s\n", green(ip), yellow("%d/%s", port.PortId, port.Protocol), fullService)
-				}
-			}
+		line := fmt.Sprintf("%s %s:%d/%s %s", v.ID, v.Host, v.Port, v.Protocol, strings.TrimSpace(v.Product+" "+v.Version))
+		if v.Exploit {
+			line += color.New(color.FgRed, color.Bold).Sprint(" [exploit]")
 		}
+		fmt.Printf("%s %s\n", severityColor(v.Severity).Sprintf("[%s %s]", strings.ToUpper(v.Severity), score), line)
+	}
+}
+
+func severityColor(severity string) *color.Color {
+	switch severity {
+	case "critical":
+		return color.New(color.FgRed, color.Bold)
+	case "high":
+		return color.New(color.FgRed)
+	case "medium":
+		return color.New(color.FgYellow)
+	case "low":
+		return color.New(color.FgGreen)
+	default:
+		return color.New(color.FgWhite)
 	}
-	fmt.Println(bold("--------------------"))
 }
@@ -0,0 +1,250 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/lair-framework/go-nmap"
+)
+
+// FlaggedServices lists services whose open ports are reported as SARIF
+// results, with the level and reason used for their rule.
+var FlaggedServices = map[string]struct {
+	Level  string
+	Reason string
+}{
+	"telnet":        {"error", "Telnet transmits credentials in cleartext"},
+	"ftp":           {"warning", "FTP transmits credentials in cleartext"},
+	"tftp":          {"warning", "TFTP has no authentication"},
+	"login":         {"error", "rlogin transmits credentials in cleartext"},
+	"shell":         {"error", "rsh allows host-based trust without passwords"},
+	"exec":          {"error", "rexec transmits credentials in cleartext"},
+	"vnc":           {"warning", "VNC exposes remote desktop access"},
+	"ms-wbt-server": {"warning", "RDP exposes remote desktop access"},
+	"microsoft-ds":  {"warning", "SMB is exposed"},
+	"netbios-ssn":   {"warning", "NetBIOS session service is exposed"},
+	"snmp":          {"warning", "SNMP may expose device configuration"},
+	"mysql":         {"warning", "Database service is exposed"},
+	"postgresql":    {"warning", "Database service is exposed"},
+	"ms-sql-s":      {"warning", "Database service is exposed"},
+	"oracle-tns":    {"warning", "Database service is exposed"},
+	"mongodb":       {"warning", "Database service is exposed"},
+	"redis":         {"error", "Redis is often deployed without authentication"},
+	"memcached":     {"warning", "Memcached has no authentication"},
+	"x11":           {"warning", "X11 display server is exposed"},
+	"docker":        {"error", "Docker API allows container control"},
+}
+
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	ShortDescription     sarifMessage      `json:"shortDescription"`
+	HelpURI              string            `json:"helpUri,omitempty"`
+	DefaultConfiguration sarifRuleConfig   `json:"defaultConfiguration"`
+	Properties           map[string]string `json:"properties,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string                 `json:"ruleId"`
+	Level               string                 `json:"level"`
+	Message             sarifMessage           `json:"message"`
+	Locations           []sarifLocation        `json:"locations"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints"`
+	Properties          map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func init() {
+	Register(&Format{
+		Name:       "sarif",
+		Title:      "SARIF",
+		Extensions: []string{".sarif"},
+		New: func(map[string]string) (Writer, error) {
+			return WriterFunc(func(res *Results, path string) error {
+				return WriteSARIF(res.Run, res.Vulns, path)
+			}), nil
+		},
+	})
+}
+
+// WriteSARIF writes flagged open services and script-detected vulnerabilities
+// as a SARIF 2.1.0 log.
+func WriteSARIF(run *nmap.NmapRun, vulns []core.Vulnerability, path string) error {
+	rules := make(map[string]sarifRule)
+	results := []sarifResult{}
+
+	for _, host := range run.Hosts {
+		ip := ""
+		if len(host.Addresses) > 0 {
+			ip = host.Addresses[0].Addr
+		}
+		for _, port := range host.Ports {
+			if port.State.State != "open" {
+				continue
+			}
+			flag, ok := FlaggedServices[port.Service.Name]
+			if !ok {
+				continue
+			}
+
+			ruleID := "service/" + port.Service.Name
+			rules[ruleID] = sarifRule{
+				ID:                   ruleID,
+				Name:                 "ExposedService",
+				ShortDescription:     sarifMessage{Text: flag.Reason},
+				DefaultConfiguration: sarifRuleConfig{Level: flag.Level},
+			}
+
+			msg := fmt.Sprintf("%s open on %s:%d/%s", port.Service.Name, ip, port.PortId, port.Protocol)
+			if product := serviceProduct(port.Service); product != "" {
+				msg += " (" + product + ")"
+			}
+			results = append(results, sarifResult{
+				RuleID:              ruleID,
+				Level:               flag.Level,
+				Message:             sarifMessage{Text: msg},
+				Locations:           sarifLocations(ip, port.PortId, port.Protocol),
+				PartialFingerprints: sarifFingerprint(ruleID, ip, port.PortId, port.Protocol),
+			})
+		}
+	}
+
+	for _, v := range vulns {
+		ruleID := v.Source + "/" + v.ID
+		level := sarifLevel(v.CVSS)
+		rules[ruleID] = sarifRule{
+			ID:                   ruleID,
+			Name:                 "VulnerableVersion",
+			ShortDescription:     sarifMessage{Text: fmt.Sprintf("%s reported by the %s script", v.ID, v.Source)},
+			HelpURI:              v.URL,
+			DefaultConfiguration: sarifRuleConfig{Level: level},
+			Properties:           map[string]string{"security-severity": strconv.FormatFloat(v.CVSS, 'f', 1, 64)},
+		}
+
+		msg := fmt.Sprintf("%s on %s:%d/%s", v.ID, v.Host, v.Port, v.Protocol)
+		if product := serviceProduct(nmap.Service{Product: v.Product, Version: v.Version}); product != "" {
+			msg += " (" + product + ")"
+		}
+		results = append(results, sarifResult{
+			RuleID:              ruleID,
+			Level:               level,
+			Message:             sarifMessage{Text: msg},
+			Locations:           sarifLocations(v.Host, v.Port, v.Protocol),
+			PartialFingerprints: sarifFingerprint(ruleID, v.Host, v.Port, v.Protocol),
+			Properties:          map[string]interface{}{"cvss": v.CVSS, "exploit": v.Exploit},
+		})
+	}
+
+	ruleList := make([]sarifRule, 0, len(rules))
+	for _, rule := range rules {
+		ruleList = append(ruleList, rule)
+	}
+	sort.Slice(ruleList, func(i, j int) bool { return ruleList[i].ID < ruleList[j].ID })
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "chainmap",
+				Version:        options.Version,
+				InformationURI: "https://github.com/ihsanlearn/chainmap",
+				Rules:          ruleList,
+			}},
+			Results: results,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// sarifLevel maps a CVSS score to a SARIF result level.
+func sarifLevel(cvss float64) string {
+	switch {
+	case cvss >= 7.0:
+		return "error"
+	case cvss >= 4.0:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// sarifLocations points a result at the service as a URI such as
+// tcp://10.0.0.1:22 or udp://[2001:db8::1]:161.
+func sarifLocations(host string, port int, protocol string) []sarifLocation {
+	scheme := strings.ToLower(protocol)
+	if scheme == "" {
+		scheme = "tcp"
+	}
+	uri := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port))
+	return []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}}}
+}
+
+func sarifFingerprint(ruleID, host string, port int, protocol string) map[string]string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d/%s", ruleID, host, port, protocol)))
+	return map[string]string{"chainmapFinding/v1": hex.EncodeToString(sum[:16])}
+}
+
+func serviceProduct(s nmap.Service) string {
+	if s.Product != "" && s.Version != "" {
+		return s.Product + " " + s.Version
+	}
+	return s.Product
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+func sarifTestRun() *nmap.NmapRun {
+	return &nmap.NmapRun{Hosts: []nmap.Host{
+		{
+			Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+			Ports: []nmap.Port{
+				{PortId: 23, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "telnet"}},
+				{PortId: 22, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "ssh"}},
+				{PortId: 6379, Protocol: "tcp", State: nmap.State{State: "closed"}, Service: nmap.Service{Name: "redis"}},
+			},
+		},
+		{
+			Addresses: []nmap.Address{{Addr: "2001:db8::1"}},
+			Ports: []nmap.Port{
+				{PortId: 161, Protocol: "udp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "snmp"}},
+			},
+		},
+	}}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	vulns := []core.Vulnerability{
+		{ID: "CVE-2021-42013", CVSS: 9.8, Source: "vulners", Host: "10.0.0.1", Port: 80, Protocol: "tcp"},
+		{ID: "CVE-2021-34798", CVSS: 5.0, Source: "vulners", Host: "10.0.0.1", Port: 80, Protocol: "tcp"},
+		{ID: "CVE-2020-0001", CVSS: 2.1, Source: "vulners", Host: "2001:db8::1", Port: 161, Protocol: "udp"},
+	}
+	path := filepath.Join(t.TempDir(), "findings.sarif")
+	if err := WriteSARIF(sarifTestRun(), vulns, path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("log = version %q with %d runs", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		rules = append(rules, rule.ID+" "+rule.DefaultConfiguration.Level)
+	}
+	wantRules := []string{
+		"service/snmp warning",
+		"service/telnet error",
+		"vulners/CVE-2020-0001 note",
+		"vulners/CVE-2021-34798 warning",
+		"vulners/CVE-2021-42013 error",
+	}
+	if !reflect.DeepEqual(rules, wantRules) {
+		t.Errorf("rules = %q, want %q", rules, wantRules)
+	}
+
+	tests := []struct {
+		ruleID string
+		level  string
+		uri    string
+	}{
+		{"service/telnet", "error", "tcp://10.0.0.1:23"},
+		{"service/snmp", "warning", "udp://[2001:db8::1]:161"},
+		{"vulners/CVE-2021-42013", "error", "tcp://10.0.0.1:80"},
+		{"vulners/CVE-2021-34798", "warning", "tcp://10.0.0.1:80"},
+		{"vulners/CVE-2020-0001", "note", "udp://[2001:db8::1]:161"},
+	}
+	if len(run.Results) != len(tests) {
+		t.Fatalf("got %d results, want %d", len(run.Results), len(tests))
+	}
+	fingerprints := map[string]bool{}
+	for i, tt := range tests {
+		res := run.Results[i]
+		uri := res.Locations[0].PhysicalLocation.ArtifactLocation.URI
+		if res.RuleID != tt.ruleID || res.Level != tt.level || uri != tt.uri {
+			t.Errorf("result %d = %s %s %s, want %s %s %s", i, res.RuleID, res.Level, uri, tt.ruleID, tt.level, tt.uri)
+		}
+		fp := res.PartialFingerprints["chainmapFinding/v1"]
+		if len(fp) != 32 || fingerprints[fp] {
+			t.Errorf("result %d fingerprint %q is not a unique 128-bit hash", i, fp)
+		}
+		fingerprints[fp] = true
+	}
+
+	// Fingerprints stay the same across runs so code scanning can track
+	// findings.
+	again := filepath.Join(t.TempDir(), "again.sarif")
+	if err := WriteSARIF(sarifTestRun(), vulns, again); err != nil {
+		t.Fatal(err)
+	}
+	if data2, _ := os.ReadFile(again); string(data2) != string(data) {
+		t.Error("WriteSARIF() output differs between runs")
+	}
+}
@@ -0,0 +1,371 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+	"golang.org/x/term"
+)
+
+// SummaryViews lists the available -summary views in display order.
+var SummaryViews = []string{"ports", "services", "port-hosts", "products", "no-open", "failed", "vulns"}
+
+// DefaultSummaryViews is used when -summary is not set.
+var DefaultSummaryViews = []string{"ports", "vulns"}
+
+// Summary holds the aggregated views of a scan. Only the selected views are
+// filled in.
+type Summary struct {
+	OpenPorts       []OpenPort           `json:"open_ports,omitempty"`
+	Services        []ServiceCount       `json:"services,omitempty"`
+	PortHosts       []PortHosts          `json:"port_hosts,omitempty"`
+	Products        []ProductCount       `json:"products,omitempty"`
+	NoOpenPorts     []string             `json:"no_open_ports,omitempty"`
+	FailedJobs      []FailedJob          `json:"failed_jobs,omitempty"`
+	Vulnerabilities []core.Vulnerability `json:"vulnerabilities,omitempty"`
+	// Downgraded is filled in regardless of the views so a scan that ran
+	// with weaker flags never goes unnoticed.
+	Downgraded []core.FlagChange `json:"downgraded,omitempty"`
+}
+
+// OpenPort is one open port of one host.
+type OpenPort struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service"`
+	Product  string `json:"product,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+// PortHosts lists the hosts that have a port open.
+type PortHosts struct {
+	Port  string   `json:"port"`
+	Hosts []string `json:"hosts"`
+}
+
+// ProductCount is a unique product and version with the number of open
+// ports it was seen on.
+type ProductCount struct {
+	Product string `json:"product"`
+	Version string `json:"version"`
+	Count   int    `json:"count"`
+}
+
+// FailedJob is a job that failed or timed out.
+type FailedJob struct {
+	Host     string `json:"host"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ParseSummaryViews turns a comma separated view list into a validated
+// selection. "all" selects every view and "none" disables the summary.
+func ParseSummaryViews(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return DefaultSummaryViews, nil
+	}
+	var selected []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "":
+			continue
+		case name == "all":
+			return SummaryViews, nil
+		case name == "none":
+			return nil, nil
+		case !hasView(SummaryViews, name):
+			return nil, fmt.Errorf("unknown summary view %q (available: %s, all, none)", name, strings.Join(SummaryViews, ","))
+		}
+		selected = append(selected, name)
+	}
+	return selected, nil
+}
+
+func hasView(views []string, name string) bool {
+	for _, v := range views {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildSummary aggregates run, its vulnerabilities and the job records into
+// the selected views.
+func BuildSummary(run *nmap.NmapRun, vulns []core.Vulnerability, records []core.JobRecord, views []string) *Summary {
+	s := &Summary{Downgraded: core.FlagChanges(records)}
+	services := make(map[string]int)
+	portHosts := make(map[string][]string)
+	products := make(map[[2]string]int)
+
+	for _, host := range run.Hosts {
+		ip := ""
+		if len(host.Addresses) > 0 {
+			ip = host.Addresses[0].Addr
+		}
+
+		open := 0
+		for _, port := range host.Ports {
+			if port.State.State != "open" {
+				continue
+			}
+			open++
+			s.OpenPorts = append(s.OpenPorts, OpenPort{
+				Host:     ip,
+				Port:     port.PortId,
+				Protocol: port.Protocol,
+				Service:  port.Service.Name,
+				Product:  port.Service.Product,
+				Version:  port.Service.Version,
+			})
+			if port.Service.Name != "" {
+				services[port.Service.Name]++
+			}
+			key := fmt.Sprintf("%d/%s", port.PortId, port.Protocol)
+			portHosts[key] = append(portHosts[key], ip)
+			if port.Service.Product != "" {
+				products[[2]string{port.Service.Product, port.Service.Version}]++
+			}
+		}
+		if open == 0 && host.Status.State == "up" {
+			s.NoOpenPorts = append(s.NoOpenPorts, ip)
+		}
+	}
+
+	s.Services = sortedCounts(services)
+
+	for port, hosts := range portHosts {
+		sort.Strings(hosts)
+		s.PortHosts = append(s.PortHosts, PortHosts{Port: port, Hosts: hosts})
+	}
+	sort.Slice(s.PortHosts, func(i, j int) bool {
+		a, b := s.PortHosts[i], s.PortHosts[j]
+		if len(a.Hosts) != len(b.Hosts) {
+			return len(a.Hosts) > len(b.Hosts)
+		}
+		return a.Port < b.Port
+	})
+
+	for pv, n := range products {
+		s.Products = append(s.Products, ProductCount{Product: pv[0], Version: pv[1], Count: n})
+	}
+	sort.Slice(s.Products, func(i, j int) bool {
+		a, b := s.Products[i], s.Products[j]
+		if a.Product != b.Product {
+			return a.Product < b.Product
+		}
+		return a.Version < b.Version
+	})
+
+	for _, rec := range records {
+		if rec.Failed() {
+			s.FailedJobs = append(s.FailedJobs, FailedJob{Host: rec.Host, Status: rec.Status, ExitCode: rec.ExitCode, Error: rec.Error})
+		}
+	}
+	s.Vulnerabilities = vulns
+
+	// Drop the views that were not selected.
+	keep := func(view string) bool { return hasView(views, view) }
+	if !keep("ports") {
+		s.OpenPorts = nil
+	}
+	if !keep("services") {
+		s.Services = nil
+	}
+	if !keep("port-hosts") {
+		s.PortHosts = nil
+	}
+	if !keep("products") {
+		s.Products = nil
+	}
+	if !keep("no-open") {
+		s.NoOpenPorts = nil
+	}
+	if !keep("failed") {
+		s.FailedJobs = nil
+	}
+	if !keep("vulns") {
+		s.Vulnerabilities = nil
+	}
+	return s
+}
+
+// WriteSummaryJSON writes the summary as indented JSON.
+func WriteSummaryJSON(s *Summary, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// PrintSummary prints the selected views as tables sized to the terminal.
+func PrintSummary(s *Summary, views []string) {
+	if len(views) == 0 {
+		return
+	}
+
+	bold := color.New(color.Bold).SprintfFunc()
+	width := terminalWidth()
+	out := os.Stdout
+
+	fmt.Fprintln(out, bold("\n--- Scan Summary ---"))
+	for _, view := range views {
+		switch view {
+		case "ports":
+			printSection(out, "Open Ports", len(s.OpenPorts))
+			rows := make([][]string, 0, len(s.OpenPorts))
+			for _, p := range s.OpenPorts {
+				rows = append(rows, []string{p.Host, fmt.Sprintf("%d/%s", p.Port, p.Protocol), p.Service, strings.TrimSpace(p.Product + " " + p.Version)})
+			}
+			printTable(out, []string{"HOST", "PORT", "SERVICE", "PRODUCT"}, rows, width)
+		case "services":
+			printSection(out, "Top Services", len(s.Services))
+			rows := make([][]string, 0, len(s.Services))
+			for _, c := range s.Services {
+				rows = append(rows, []string{c.Name, strconv.Itoa(c.Count)})
+			}
+			printTable(out, []string{"SERVICE", "OPEN PORTS"}, rows, width)
+		case "port-hosts":
+			printSection(out, "Hosts per Port", len(s.PortHosts))
+			rows := make([][]string, 0, len(s.PortHosts))
+			for _, p := range s.PortHosts {
+				rows = append(rows, []string{p.Port, strconv.Itoa(len(p.Hosts)), strings.Join(p.Hosts, " ")})
+			}
+			printTable(out, []string{"PORT", "COUNT", "HOSTS"}, rows, width)
+		case "products":
+			printSection(out, "Products", len(s.Products))
+			rows := make([][]string, 0, len(s.Products))
+			for _, p := range s.Products {
+				rows = append(rows, []string{p.Product, p.Version, strconv.Itoa(p.Count)})
+			}
+			printTable(out, []string{"PRODUCT", "VERSION", "COUNT"}, rows, width)
+		case "no-open":
+			printSection(out, "Hosts Without Open Ports", len(s.NoOpenPorts))
+			rows := make([][]string, 0, len(s.NoOpenPorts))
+			for _, h := range s.NoOpenPorts {
+				rows = append(rows, []string{h})
+			}
+			printTable(out, []string{"HOST"}, rows, width)
+		case "failed":
+			printSection(out, "Failed Jobs", len(s.FailedJobs))
+			rows := make([][]string, 0, len(s.FailedJobs))
+			for _, j := range s.FailedJobs {
+				rows = append(rows, []string{j.Host, j.Status, strconv.Itoa(j.ExitCode), j.Error})
+			}
+			printTable(out, []string{"HOST", "STATUS", "EXIT", "ERROR"}, rows, width)
+		case "vulns":
+			printVulnerabilities(s.Vulnerabilities)
+		}
+	}
+	if len(s.Downgraded) > 0 {
+		printSection(out, "Downgraded Flags", len(s.Downgraded))
+		rows := make([][]string, 0, len(s.Downgraded))
+		for _, c := range s.Downgraded {
+			to := c.To
+			if to == "" {
+				to = "dropped"
+			}
+			rows = append(rows, []string{c.From, to, c.Reason})
+		}
+		printTable(out, []string{"FLAG", "REPLACEMENT", "REASON"}, rows, width)
+	}
+	fmt.Fprintln(out, bold("--------------------"))
+}
+
+func printSection(w io.Writer, title string, n int) {
+	fmt.Fprintln(w, color.New(color.Bold).Sprintf("\n%s (%d)", title, n))
+}
+
+// printTable prints rows in aligned columns. When the table is wider than
+// width, the widest columns are truncated until it fits.
+func printTable(w io.Writer, headers []string, rows [][]string, width int) {
+	if len(rows) == 0 {
+		return
+	}
+
+	const gap = 2
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	total := func() int {
+		sum := gap * (len(widths) - 1)
+		for _, n := range widths {
+			sum += n
+		}
+		return sum
+	}
+	for total() > width {
+		widest := 0
+		for i, n := range widths {
+			if n > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= 8 {
+			break
+		}
+		widths[widest]--
+	}
+
+	bold := color.New(color.Bold).SprintFunc()
+	printRow := func(row []string, header bool) {
+		var b strings.Builder
+		for i, cell := range row {
+			cell = truncate(cell, widths[i])
+			if header {
+				b.WriteString(bold(cell))
+			} else {
+				b.WriteString(cell)
+			}
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+gap))
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(b.String(), " "))
+	}
+
+	printRow(headers, true)
+	for _, row := range rows {
+		printRow(row, false)
+	}
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+// terminalWidth returns the width of the terminal on stdout, then $COLUMNS,
+// then a default of 120 for pipes.
+func terminalWidth() int {
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 120
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+func TestParseSummaryViews(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{"", DefaultSummaryViews, false},
+		{"ports,failed", []string{"ports", "failed"}, false},
+		{" Services , ,PORT-HOSTS", []string{"services", "port-hosts"}, false},
+		{"ports,all", SummaryViews, false},
+		{"none", nil, false},
+		{"ports,nope", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseSummaryViews(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSummaryViews() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSummaryViews() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func summaryTestRun() *nmap.NmapRun {
+	port := func(id int, state, name, product, version string) nmap.Port {
+		return nmap.Port{PortId: id, Protocol: "tcp", State: nmap.State{State: state},
+			Service: nmap.Service{Name: name, Product: product, Version: version}}
+	}
+	return &nmap.NmapRun{Hosts: []nmap.Host{
+		{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.2"}},
+			Ports:     []nmap.Port{port(22, "open", "ssh", "OpenSSH", "8.2p1"), port(80, "open", "http", "nginx", "")},
+		},
+		{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.1"}},
+			Ports:     []nmap.Port{port(22, "open", "ssh", "OpenSSH", "8.2p1"), port(443, "closed", "https", "", "")},
+		},
+		{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.3"}},
+			Ports:     []nmap.Port{port(443, "filtered", "https", "", "")},
+		},
+		{
+			Status:    nmap.Status{State: "down"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.4"}},
+		},
+	}}
+}
+
+func TestBuildSummary(t *testing.T) {
+	records := []core.JobRecord{
+		{ID: 1, Host: "10.0.0.1", Status: "ok"},
+		{ID: 2, Host: "10.0.0.5", Status: "timeout", ExitCode: -1, Error: "job timed out"},
+	}
+	vulns := []core.Vulnerability{{ID: "CVE-2021-41773", Host: "10.0.0.2"}}
+
+	s := BuildSummary(summaryTestRun(), vulns, records, SummaryViews)
+
+	wantPorts := []OpenPort{
+		{Host: "10.0.0.2", Port: 22, Protocol: "tcp", Service: "ssh", Product: "OpenSSH", Version: "8.2p1"},
+		{Host: "10.0.0.2", Port: 80, Protocol: "tcp", Service: "http", Product: "nginx"},
+		{Host: "10.0.0.1", Port: 22, Protocol: "tcp", Service: "ssh", Product: "OpenSSH", Version: "8.2p1"},
+	}
+	if !reflect.DeepEqual(s.OpenPorts, wantPorts) {
+		t.Errorf("OpenPorts = %+v, want %+v", s.OpenPorts, wantPorts)
+	}
+	if want := []ServiceCount{{"ssh", 2}, {"http", 1}}; !reflect.DeepEqual(s.Services, want) {
+		t.Errorf("Services = %v, want %v", s.Services, want)
+	}
+	wantPortHosts := []PortHosts{
+		{Port: "22/tcp", Hosts: []string{"10.0.0.1", "10.0.0.2"}},
+		{Port: "80/tcp", Hosts: []string{"10.0.0.2"}},
+	}
+	if !reflect.DeepEqual(s.PortHosts, wantPortHosts) {
+		t.Errorf("PortHosts = %+v, want %+v", s.PortHosts, wantPortHosts)
+	}
+	wantProducts := []ProductCount{{"OpenSSH", "8.2p1", 2}, {"nginx", "", 1}}
+	if !reflect.DeepEqual(s.Products, wantProducts) {
+		t.Errorf("Products = %+v, want %+v", s.Products, wantProducts)
+	}
+	if want := []string{"10.0.0.3"}; !reflect.DeepEqual(s.NoOpenPorts, want) {
+		t.Errorf("NoOpenPorts = %q, want %q", s.NoOpenPorts, want)
+	}
+	wantFailed := []FailedJob{{Host: "10.0.0.5", Status: "timeout", ExitCode: -1, Error: "job timed out"}}
+	if !reflect.DeepEqual(s.FailedJobs, wantFailed) {
+		t.Errorf("FailedJobs = %+v, want %+v", s.FailedJobs, wantFailed)
+	}
+	if !reflect.DeepEqual(s.Vulnerabilities, vulns) {
+		t.Errorf("Vulnerabilities = %+v", s.Vulnerabilities)
+	}
+
+	// Views that are not selected stay empty.
+	s = BuildSummary(summaryTestRun(), vulns, records, []string{"services"})
+	if s.Services == nil || s.OpenPorts != nil || s.PortHosts != nil || s.Products != nil ||
+		s.NoOpenPorts != nil || s.FailedJobs != nil || s.Vulnerabilities != nil {
+		t.Errorf("BuildSummary(services) = %+v", s)
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	headers := []string{"HOST", "PORT", "PRODUCT"}
+	rows := [][]string{
+		{"10.0.0.1", "22/tcp", "OpenSSH 8.2p1 Ubuntu"},
+		{"10.0.0.10", "80/tcp", ""},
+	}
+	tests := []struct {
+		name  string
+		rows  [][]string
+		width int
+		want  string
+	}{
+		{"No rows", nil, 80, ""},
+		{
+			"Fits",
+			rows,
+			80,
+			"HOST       PORT    PRODUCT\n" +
+				"10.0.0.1   22/tcp  OpenSSH 8.2p1 Ubuntu\n" +
+				"10.0.0.10  80/tcp\n",
+		},
+		{
+			"Widest column truncated",
+			rows,
+			30,
+			"HOST       PORT    PRODUCT\n" +
+				"10.0.0.1   22/tcp  OpenSSH 8.…\n" +
+				"10.0.0.10  80/tcp\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printTable(&buf, headers, tt.rows, tt.width)
+			if buf.String() != tt.want {
+				t.Errorf("printTable() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+// Results are the merged scan results handed to every output writer.
+type Results struct {
+	Run   *nmap.NmapRun
+	Vulns []core.Vulnerability
+	// Commands are the shell-quoted nmap commands of the jobs.
+	Commands []string
+	// Downgraded lists the nmap flags rewritten by -auto-downgrade.
+	Downgraded []core.FlagChange
+}
+
+// Writer writes results to path in one output format.
+type Writer interface {
+	Write(res *Results, path string) error
+}
+
+// WriterFunc adapts a function to Writer.
+type WriterFunc func(res *Results, path string) error
+
+func (f WriterFunc) Write(res *Results, path string) error {
+	return f(res, path)
+}
+
+// Format is an output format that -o can select, by its extension or with
+// a "name:" prefix.
+type Format struct {
+	// Name selects the format in "name:path" and ?format= of the API.
+	Name string
+	// Title names the format in logs.
+	Title      string
+	Extensions []string
+	// Options maps the option keys the format accepts to their meaning.
+	Options map[string]string
+	// New returns a writer configured with opts, which only holds keys
+	// listed in Options.
+	New func(opts map[string]string) (Writer, error)
+}
+
+var formats = map[string]*Format{}
+
+// Register makes a format available to -o. Formats register from init
+// functions, so a duplicate name or extension panics.
+func Register(f *Format) {
+	if _, ok := formats[f.Name]; ok {
+		panic("report: format " + f.Name + " registered twice")
+	}
+	for _, ext := range f.Extensions {
+		if other := formatForExt(ext); other != nil {
+			panic("report: extension " + ext + " used by " + other.Name + " and " + f.Name)
+		}
+	}
+	formats[f.Name] = f
+}
+
+// Formats returns the registered formats ordered by name.
+func Formats() []*Format {
+	list := make([]*Format, 0, len(formats))
+	for _, f := range formats {
+		list = append(list, f)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupFormat returns the format registered as name.
+func LookupFormat(name string) *Format {
+	return formats[strings.ToLower(name)]
+}
+
+func formatForExt(ext string) *Format {
+	ext = strings.ToLower(ext)
+	for _, f := range formats {
+		for _, e := range f.Extensions {
+			if e == ext {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// Output is one parsed -o value.
+type Output struct {
+	Format  *Format
+	Path    string
+	Options map[string]string
+	writer  Writer
+}
+
+// Write writes res to the output's path.
+func (o *Output) Write(res *Results) error {
+	return o.writer.Write(res, o.Path)
+}
+
+// ParseOutput parses an -o value of the form "[format:]path[?key=value&...]".
+// Without a format prefix the format follows from the extension of path.
+// defaults supplies option values, such as those of -columns, to every
+// format that accepts them and does not set them itself.
+func ParseOutput(spec string, defaults map[string]string) (*Output, error) {
+	out := &Output{Path: spec, Options: map[string]string{}}
+	if i := strings.LastIndex(spec, "?"); i >= 0 {
+		query, err := url.ParseQuery(spec[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid options in output %q: %w", spec, err)
+		}
+		for key := range query {
+			out.Options[key] = query.Get(key)
+		}
+		out.Path = spec[:i]
+	}
+	if name, path, ok := strings.Cut(out.Path, ":"); ok && LookupFormat(name) != nil {
+		out.Format, out.Path = LookupFormat(name), path
+	} else {
+		out.Format = formatForExt(filepath.Ext(out.Path))
+	}
+	if out.Path == "" {
+		return nil, fmt.Errorf("output %q has no file name", spec)
+	}
+	if out.Format == nil {
+		return nil, fmt.Errorf("unsupported output format %q for %s, use one of %s", filepath.Ext(out.Path), out.Path, formatNames())
+	}
+
+	for key := range out.Options {
+		if _, ok := out.Format.Options[key]; !ok {
+			return nil, fmt.Errorf("%s output does not take option %q", out.Format.Name, key)
+		}
+	}
+	for key, value := range defaults {
+		if _, ok := out.Format.Options[key]; ok && value != "" {
+			if _, set := out.Options[key]; !set {
+				out.Options[key] = value
+			}
+		}
+	}
+
+	writer, err := out.Format.New(out.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%s output %s: %w", out.Format.Name, out.Path, err)
+	}
+	out.writer = writer
+	return out, nil
+}
+
+// ParseOutputs parses every -o value. Two outputs may not share a path.
+func ParseOutputs(specs []string, defaults map[string]string) ([]*Output, error) {
+	var outputs []*Output
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		out, err := ParseOutput(spec, defaults)
+		if err != nil {
+			return nil, err
+		}
+		if seen[out.Path] {
+			return nil, fmt.Errorf("output %s given twice", out.Path)
+		}
+		seen[out.Path] = true
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+func formatNames() string {
+	var names []string
+	for _, f := range Formats() {
+		names = append(names, f.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,16 @@
+package report
+
+import "github.com/ihsanlearn/chainmap/core"
+
+func init() {
+	Register(&Format{
+		Name:       "xml",
+		Title:      "XML",
+		Extensions: []string{".xml"},
+		New: func(map[string]string) (Writer, error) {
+			return WriterFunc(func(res *Results, path string) error {
+				return core.WriteXML(res.Run, path)
+			}), nil
+		},
+	})
+}
@@ -0,0 +1,164 @@
+package runner
+
+import (
+	"sync"
+
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+// scanStatus is the outcome of a single nmap job.
+type scanStatus int
+
+const (
+	scanOK scanStatus = iota
+	scanTimeout
+	scanHostDown
+	scanFailed
+	// scanSkipped marks jobs that never ran because the -budget ran out.
+	scanSkipped
+)
+
+func (s scanStatus) String() string {
+	switch s {
+	case scanTimeout:
+		return "timeout"
+	case scanHostDown:
+		return "down"
+	case scanFailed:
+		return "failed"
+	case scanSkipped:
+		return "not_scanned"
+	default:
+		return "ok"
+	}
+}
+
+// adaptiveWindow is the number of recent outcomes used to decide when to back off.
+const adaptiveWindow = 10
+
+// concurrencyLimiter bounds the number of running nmap jobs. With min == max it
+// behaves like a fixed worker pool; otherwise it starts at min, grows by one
+// after a run of clean scans and halves when failures pile up.
+type concurrencyLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	min    int
+	max    int
+	limit  int
+	active int
+
+	outcomes []scanStatus
+	streak   int
+}
+
+func newConcurrencyLimiter(min, max int) *concurrencyLimiter {
+	if max < 1 {
+		max = 1
+	}
+	if min < 1 {
+		min = 1
+	}
+	if min > max {
+		min = max
+	}
+	l := &concurrencyLimiter{min: min, max: max, limit: min}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *concurrencyLimiter) adaptive() bool {
+	return l.min != l.max
+}
+
+// acquire blocks until a slot is free under the current limit.
+func (l *concurrencyLimiter) acquire() {
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+// release frees a slot without recording an outcome.
+func (l *concurrencyLimiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Broadcast()
+}
+
+// record frees a slot and feeds the job outcome into the limit calculation.
+func (l *concurrencyLimiter) record(status scanStatus) {
+	l.mu.Lock()
+	l.active--
+	previous := l.limit
+
+	// Changes are logged after unlocking since the progress display reads
+	// the current limit while redrawing around log lines.
+	var change func()
+
+	if l.adaptive() {
+		l.outcomes = append(l.outcomes, status)
+		if len(l.outcomes) > adaptiveWindow {
+			l.outcomes = l.outcomes[1:]
+		}
+
+		if status == scanOK {
+			l.streak++
+			if l.streak >= l.limit && l.limit < l.max {
+				l.limit++
+				l.streak = 0
+				limit := l.limit
+				change = func() { logger.Info("Scans completing cleanly, raising concurrency to %d", limit) }
+			}
+		} else {
+			l.streak = 0
+			timeouts, down, failed := l.failures()
+			total := timeouts + down + failed
+			if len(l.outcomes) >= 3 && total*10 >= len(l.outcomes)*3 && l.limit > l.min {
+				l.limit /= 2
+				if l.limit < l.min {
+					l.limit = l.min
+				}
+				l.outcomes = l.outcomes[:0]
+				limit := l.limit
+				change = func() {
+					logger.Info("Backing off to concurrency %d (%d timeouts, %d hosts down, %d nmap errors in recent scans)", limit, timeouts, down, failed)
+				}
+			}
+		}
+	}
+
+	delta := l.limit - previous
+	l.mu.Unlock()
+	l.cond.Broadcast()
+
+	if delta != 0 {
+		concurrency.Add(float64(delta))
+	}
+	if change != nil {
+		change()
+	}
+}
+
+// current returns the active concurrency limit.
+func (l *concurrencyLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+func (l *concurrencyLimiter) failures() (timeouts, down, failed int) {
+	for _, s := range l.outcomes {
+		switch s {
+		case scanTimeout:
+			timeouts++
+		case scanHostDown:
+			down++
+		case scanFailed:
+			failed++
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewConcurrencyLimiter(t *testing.T) {
+	tests := []struct {
+		min, max         int
+		wantMin, wantMax int
+		adaptive         bool
+	}{
+		{1, 4, 1, 4, true},
+		{3, 3, 3, 3, false},
+		{0, 0, 1, 1, false},
+		{-1, 4, 1, 4, true},
+		{5, 2, 2, 2, false},
+	}
+	for _, tt := range tests {
+		l := newConcurrencyLimiter(tt.min, tt.max)
+		if l.min != tt.wantMin || l.max != tt.wantMax || l.current() != tt.wantMin || l.adaptive() != tt.adaptive {
+			t.Errorf("newConcurrencyLimiter(%d, %d) = min %d, max %d, limit %d, adaptive %v",
+				tt.min, tt.max, l.min, l.max, l.current(), l.adaptive())
+		}
+	}
+}
+
+// outcomes spells a run of job results: o ok, t timeout, d host down,
+// f nmap error.
+func outcomes(s string) []scanStatus {
+	codes := map[rune]scanStatus{'o': scanOK, 't': scanTimeout, 'd': scanHostDown, 'f': scanFailed}
+	var res []scanStatus
+	for _, c := range s {
+		res = append(res, codes[c])
+	}
+	return res
+}
+
+func TestConcurrencyLimiterRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		outcomes string
+		want     int
+	}{
+		{"Grows after a streak as long as the limit", 1, 4, "o" + "oo" + "ooo", 4},
+		{"Streak not long enough", 1, 4, "o" + "o", 2},
+		{"Clamped to max", 1, 3, strings.Repeat("o", 20), 3},
+		{"Fixed pool", 3, 3, "ooooottttt", 3},
+		{"Halves at 30% failures", 1, 8, strings.Repeat("o", 28) + "tdf", 4},
+		{"Below 30% failures", 1, 8, strings.Repeat("o", 28) + "tdo", 8},
+		{"Halves again on a fresh window", 1, 8, strings.Repeat("o", 28) + "tdf" + "ttt", 2},
+		{"Clamped to min", 3, 8, "ooo" + "fff", 3},
+		{"Needs three outcomes", 1, 4, "o" + "t", 2},
+		{"Failure resets the streak", 1, 8, "oooooo" + "ooo" + "f" + "ooo", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newConcurrencyLimiter(tt.min, tt.max)
+			for _, status := range outcomes(tt.outcomes) {
+				l.acquire()
+				l.record(status)
+			}
+			if got := l.current(); got != tt.want {
+				t.Errorf("limit = %d, want %d", got, tt.want)
+			}
+			if l.active != 0 {
+				t.Errorf("active = %d after every slot was freed", l.active)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterAcquire(t *testing.T) {
+	l := newConcurrencyLimiter(1, 1)
+	l.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire() did not wait for a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	l.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire() still blocked after release()")
+	}
+	l.release()
+	if l.current() != 1 || l.active != 0 {
+		t.Errorf("release() changed the limit to %d, active %d", l.current(), l.active)
+	}
+}
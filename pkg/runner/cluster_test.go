@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/internal/nmaptest"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func clusterPost(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+func TestCoordinatorWithWorkers(t *testing.T) {
+	nmaptest.Install(t)
+	leaseTTL, pollInterval = 300*time.Millisecond, 20*time.Millisecond
+	t.Cleanup(func() { leaseTTL, pollInterval = 30*time.Second, 2*time.Second })
+
+	dir := t.TempDir()
+	addr := freeAddr(t)
+	hosts := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	opts := &options.Options{
+		Targets:      hosts,
+		Threads:      5,
+		MinThreads:   1,
+		Timeout:      1,
+		Outputs:      []string{filepath.Join(dir, "results.xml")},
+		StateDir:     filepath.Join(dir, "state"),
+		Summary:      "none",
+		NoProgress:   true,
+		Coordinator:  addr,
+		ClusterToken: "secret",
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- New(opts).Run() }()
+
+	// A worker that takes a job and disappears, so the job must be
+	// reassigned once its lease expires.
+	base := "http://" + addr
+	var lease Lease
+	deadline := time.Now().Add(5 * time.Second)
+	for lease.Token == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("coordinator did not hand out a job")
+		}
+		if resp := clusterPost(t, base+"/cluster/lease", `{"worker":"dead"}`); resp != nil {
+			json.NewDecoder(resp.Body).Decode(&lease)
+			resp.Body.Close()
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp, err := http.Post(base+"/cluster/lease", "application/json", strings.NewReader(`{"worker":"intruder"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("lease without token status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, name := range []string{"w1", "w2"} {
+		wg.Add(1)
+		wopts := &options.Options{Threads: 2, StateDir: filepath.Join(dir, name)}
+		go func(w *Worker) {
+			defer wg.Done()
+			w.Run(ctx)
+		}(NewWorker(wopts, base, "secret", name))
+	}
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("coordinator did not finish")
+	}
+	cancel()
+	wg.Wait()
+
+	run, err := core.ParseXML(opts.Outputs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Hosts) != len(hosts) {
+		t.Errorf("merged hosts = %d, want %d", len(run.Hosts), len(hosts))
+	}
+
+	data, err := os.ReadFile(filepath.Join(opts.StateDir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest core.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Jobs) != len(hosts) {
+		t.Fatalf("manifest jobs = %d, want %d", len(manifest.Jobs), len(hosts))
+	}
+	for _, rec := range manifest.Jobs {
+		if rec.Worker != "w1" && rec.Worker != "w2" {
+			t.Errorf("job %s ran on %q", rec.Host, rec.Worker)
+		}
+		if rec.Status != scanOK.String() {
+			t.Errorf("job %s status = %s", rec.Host, rec.Status)
+		}
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	tests := []struct {
+		name    string
+		opts    options.Options
+		wantErr bool
+	}{
+		{"Scan", options.Options{}, true},
+		{"Worker", options.Options{ClusterToken: "secret"}, true},
+		{"Coordinator", options.Options{Coordinator: "127.0.0.1:9090"}, false},
+		{"Dry run", options.Options{DryRun: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New(&tt.opts).CheckDependencies(); (err != nil) != tt.wantErr {
+				t.Errorf("CheckDependencies() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
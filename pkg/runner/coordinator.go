@@ -0,0 +1,395 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/pkg/progress"
+)
+
+// leaseTTL is how long a worker may go without a heartbeat before its job
+// is handed to another worker.
+var leaseTTL = 30 * time.Second
+
+// maxAttempts is how many leases a job gets before it is marked failed.
+const maxAttempts = 3
+
+// Lease is a job handed to a worker.
+type Lease struct {
+	JobID     int      `json:"job_id"`
+	Token     string   `json:"token"`
+	Host      string   `json:"host"`
+	Ports     []string `json:"ports,omitempty"`
+	NmapFlags string   `json:"nmap_flags"`
+	// Timeout is the job's nmap timeout, already capped by the budget.
+	Timeout time.Duration `json:"timeout"`
+	// Scripts is the -auto-scripts map, when enabled on the coordinator.
+	Scripts core.ScriptMap `json:"scripts,omitempty"`
+}
+
+// LeaseRequest identifies the worker asking for a job.
+type LeaseRequest struct {
+	Worker string `json:"worker"`
+}
+
+// JobResult is what a worker reports for a leased job.
+type JobResult struct {
+	Token  string         `json:"token"`
+	Record core.JobRecord `json:"record"`
+	XML    string         `json:"xml,omitempty"`
+}
+
+type leaseState int
+
+const (
+	leasePending leaseState = iota
+	leaseActive
+	leaseDone
+)
+
+type clusterJob struct {
+	job      *job
+	state    leaseState
+	token    string
+	worker   string
+	slot     int
+	deadline time.Time
+	attempts int
+}
+
+// coordinator hands jobs to remote workers and collects their results.
+type coordinator struct {
+	r         *Runner
+	outputDir string
+
+	mu        sync.Mutex
+	jobs      map[int]*clusterJob
+	order     []int
+	remaining int
+	nextSlot  int
+	done      chan struct{}
+}
+
+// runCoordinator serves jobList to chainmap workers on the -coordinator
+// address and waits until every job has a result or ctx is cancelled.
+func (r *Runner) runCoordinator(ctx context.Context, jobList []*job, outputDir string) error {
+	c := &coordinator{
+		r:         r,
+		outputDir: outputDir,
+		jobs:      make(map[int]*clusterJob),
+		remaining: len(jobList),
+		done:      make(chan struct{}),
+	}
+	for _, j := range jobList {
+		c.jobs[j.ID] = &clusterJob{job: j}
+		c.order = append(c.order, j.ID)
+	}
+	if len(jobList) == 0 {
+		return nil
+	}
+
+	// Listen first: Run only stops the progress display when this returns
+	// nil.
+	ln, err := net.Listen("tcp", r.options.Coordinator)
+	if err != nil {
+		return fmt.Errorf("coordinator failed to listen: %w", err)
+	}
+	if r.showProgress() {
+		r.progress = progress.New(len(jobList), r.options.StatsEvery)
+		logger.SetOutput(r.progress.Wrap(os.Stderr))
+		r.progress.Start()
+	}
+	jobsQueued.Add(float64(len(jobList)))
+	defer c.clearGauges()
+	srv := &http.Server{Handler: c.handler(r.options.ClusterToken)}
+	go srv.Serve(ln)
+	defer func() {
+		// Let in-flight result uploads get their response.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	logger.Info("Coordinator waiting for workers on %s (%d jobs)", ln.Addr(), len(jobList))
+
+	ticker := time.NewTicker(leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.expireLeases()
+			if r.budgetExhausted() {
+				c.skipPending()
+			}
+		}
+	}
+}
+
+func (c *coordinator) handler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /cluster/lease", c.handleLease)
+	mux.HandleFunc("POST /cluster/jobs/{id}/heartbeat", c.handleHeartbeat)
+	mux.HandleFunc("POST /cluster/jobs/{id}/result", c.handleResult)
+
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, req)
+	})
+}
+
+// handleLease hands out the next pending job, or 204 when none is left.
+func (c *coordinator) handleLease(w http.ResponseWriter, req *http.Request) {
+	var lr LeaseRequest
+	if err := json.NewDecoder(req.Body).Decode(&lr); err != nil || lr.Worker == "" {
+		http.Error(w, "worker name required", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	var cj *clusterJob
+	for _, id := range c.order {
+		if c.jobs[id].state == leasePending {
+			cj = c.jobs[id]
+			break
+		}
+	}
+	if cj == nil {
+		c.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	timeout, ok := c.r.jobTimeout(cj.job)
+	if !ok {
+		c.mu.Unlock()
+		c.skipPending()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	cj.state = leaseActive
+	jobsQueued.Add(-1)
+	jobsRunning.Add(1)
+	cj.token = newLeaseToken()
+	cj.worker = lr.Worker
+	cj.deadline = time.Now().Add(leaseTTL)
+	cj.attempts++
+	c.nextSlot++
+	cj.slot = c.nextSlot
+	lease := Lease{
+		JobID:     cj.job.ID,
+		Token:     cj.token,
+		Host:      cj.job.Host,
+		Ports:     cj.job.Ports,
+		NmapFlags: c.r.nmapFlags(),
+		Timeout:   timeout,
+		Scripts:   c.r.scriptMap,
+	}
+	c.mu.Unlock()
+
+	cj.job.log.Info("Assigned %s to worker %s", cj.job.Host, lr.Worker)
+	if c.r.progress != nil {
+		c.r.progress.JobStarted(cj.slot, cj.job.Host).Close()
+	}
+	writeClusterJSON(w, lease)
+}
+
+// handleHeartbeat extends a lease. A 409 tells the worker it lost the job.
+func (c *coordinator) handleHeartbeat(w http.ResponseWriter, req *http.Request) {
+	var res JobResult
+	if err := json.NewDecoder(req.Body).Decode(&res); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cj := c.lookup(req)
+	if cj == nil || cj.state != leaseActive || cj.token != res.Token {
+		http.Error(w, "lease lost", http.StatusConflict)
+		return
+	}
+	cj.deadline = time.Now().Add(leaseTTL)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResult stores the XML of a finished job. Results for leases that
+// were reassigned are rejected so every job is counted once.
+func (c *coordinator) handleResult(w http.ResponseWriter, req *http.Request) {
+	var res JobResult
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 256<<20)).Decode(&res); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	cj := c.lookup(req)
+	if cj == nil || cj.state != leaseActive || cj.token != res.Token {
+		c.mu.Unlock()
+		http.Error(w, "lease lost", http.StatusConflict)
+		return
+	}
+	cj.state = leaseDone
+	jobsRunning.Add(-1)
+	worker, slot := cj.worker, cj.slot
+	c.mu.Unlock()
+
+	j := cj.job
+	record := res.Record
+	record.ID, record.Host, record.Ports, record.Worker = j.ID, j.Host, j.Ports, worker
+
+	if res.XML != "" {
+		path := filepath.Join(c.outputDir, safeName(j.Host)+".xml")
+		if err := os.WriteFile(path, []byte(res.XML), 0644); err != nil {
+			j.log.Error("Failed to store result from %s: %s", worker, err)
+			record.Status, record.Error = scanFailed.String(), err.Error()
+		}
+	}
+	c.complete(j, record, slot)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookup returns the job named in the request path. The caller holds c.mu.
+func (c *coordinator) lookup(req *http.Request) *clusterJob {
+	var id int
+	if _, err := fmt.Sscan(req.PathValue("id"), &id); err != nil {
+		return nil
+	}
+	return c.jobs[id]
+}
+
+// expireLeases requeues jobs whose worker stopped sending heartbeats.
+func (c *coordinator) expireLeases() {
+	now := time.Now()
+	var failed []*clusterJob
+
+	c.mu.Lock()
+	for _, id := range c.order {
+		cj := c.jobs[id]
+		if cj.state != leaseActive || now.Before(cj.deadline) {
+			continue
+		}
+		if cj.attempts >= maxAttempts {
+			cj.state = leaseDone
+			jobsRunning.Add(-1)
+			failed = append(failed, cj)
+			continue
+		}
+		cj.job.log.Warn("Worker %s stopped responding, reassigning %s", cj.worker, cj.job.Host)
+		if c.r.progress != nil {
+			c.r.progress.JobRequeued(cj.slot)
+		}
+		cj.state = leasePending
+		jobsQueued.Add(1)
+		jobsRunning.Add(-1)
+		cj.token = ""
+	}
+	c.mu.Unlock()
+
+	for _, cj := range failed {
+		cj.job.log.Error("Giving up on %s after %d attempts", cj.job.Host, cj.attempts)
+		c.complete(cj.job, core.JobRecord{
+			ID:       cj.job.ID,
+			Host:     cj.job.Host,
+			Ports:    cj.job.Ports,
+			Status:   scanFailed.String(),
+			ExitCode: -1,
+			Worker:   cj.worker,
+			Error:    fmt.Sprintf("no worker finished the job after %d attempts", cj.attempts),
+		}, cj.slot)
+	}
+}
+
+// skipPending records every job that was not leased yet as not scanned.
+func (c *coordinator) skipPending() {
+	var skipped []*job
+	c.mu.Lock()
+	for _, id := range c.order {
+		if cj := c.jobs[id]; cj.state == leasePending {
+			cj.state = leaseDone
+			jobsQueued.Add(-1)
+			skipped = append(skipped, cj.job)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, j := range skipped {
+		c.r.skipJob(j)
+		c.finishOne()
+	}
+}
+
+// complete records a finished job.
+func (c *coordinator) complete(j *job, record core.JobRecord, slot int) {
+	c.r.addRecord(record)
+	if c.r.progress != nil {
+		c.r.progress.JobFinished(slot, record.Failed())
+	}
+	result, _ := core.ParseXML(filepath.Join(c.outputDir, safeName(j.Host)+".xml"))
+	observeJob(record, result)
+	if c.r.OnJob != nil {
+		c.r.OnJob(record, result)
+	}
+	c.finishOne()
+}
+
+// finishOne counts a settled job and closes c.done after the last one.
+func (c *coordinator) finishOne() {
+	c.mu.Lock()
+	c.remaining--
+	last := c.remaining == 0
+	c.mu.Unlock()
+	if last {
+		close(c.done)
+	}
+}
+
+// clearGauges takes the jobs left behind by a cancelled run out of the
+// queued and running gauges.
+func (c *coordinator) clearGauges() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cj := range c.jobs {
+		switch cj.state {
+		case leasePending:
+			jobsQueued.Add(-1)
+		case leaseActive:
+			jobsRunning.Add(-1)
+		}
+		cj.state = leaseDone
+	}
+}
+
+func newLeaseToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeClusterJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// errLeaseLost is returned to a worker whose job was reassigned.
+var errLeaseLost = errors.New("lease lost")
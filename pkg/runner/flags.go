@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/google/shlex"
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+)
+
+// detectPrivileges reports the raw socket access of the nmap in PATH.
+func detectPrivileges() privileges.Capabilities {
+	nmapPath, _ := exec.LookPath("nmap")
+	caps := privileges.Detect(nmapPath)
+	logger.Debug("nmap privileges: %s", caps)
+	return caps
+}
+
+// loadFlagPolicy reads the -flag-policy file.
+func (r *Runner) loadFlagPolicy() error {
+	if r.options.FlagPolicy == "" {
+		return nil
+	}
+	policy, err := core.LoadFlagPolicy(r.options.FlagPolicy)
+	if err != nil {
+		return fmt.Errorf("could not load flag policy: %w", err)
+	}
+	r.policy = policy
+	return nil
+}
+
+// flagFit records what prepareFlags changed in the scan flags.
+type flagFit struct {
+	caps        *privileges.Capabilities
+	removed     []string
+	changes     []core.FlagChange
+	unsupported []string
+}
+
+// prepareFlags checks the scan flags against the flag policy and fits them
+// to the privileges nmap runs with. With nil caps only the policy applies,
+// as on a coordinator whose workers check their own privileges.
+func (r *Runner) prepareFlags(caps *privileges.Capabilities) (*flagFit, error) {
+	args, err := shlex.Split(r.nmapFlags())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nmap flags: %w", err)
+	}
+	fit := &flagFit{caps: caps}
+	if args, fit.removed, err = core.SanitizeFlags(args, r.policy); err != nil {
+		return nil, fmt.Errorf("invalid nmap flags: %w", err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid nmap flags: nothing left after removing %s", strings.Join(fit.removed, ", "))
+	}
+	if caps != nil {
+		args, fit.changes, fit.unsupported = fitPrivileges(args, *caps, r.options.AutoDowngrade)
+	}
+	r.flags = shellJoin(args)
+	r.downgraded = fit.changes
+	return fit, nil
+}
+
+// fitPrivileges adjusts nmap arguments to the raw socket access in caps.
+// nmap running on capabilities alone is given --privileged. Without raw
+// socket access the arguments that need it are rewritten when downgrade is
+// set; otherwise they are kept and returned in unsupported.
+func fitPrivileges(args []string, caps privileges.Capabilities, downgrade bool) (fitted []string, changes []core.FlagChange, unsupported []string) {
+	if caps.NeedsPrivilegedFlag() {
+		for _, arg := range args {
+			if arg == "--privileged" {
+				return args, nil, nil
+			}
+		}
+		return append(args, "--privileged"), nil, nil
+	}
+	if caps.Privileged() {
+		return args, nil, nil
+	}
+
+	downgraded, changes := core.DowngradeFlags(args)
+	if !downgrade {
+		for _, c := range changes {
+			unsupported = append(unsupported, c.From)
+		}
+		return args, nil, unsupported
+	}
+	return downgraded, changes, nil
+}
+
+// report logs what prepareFlags changed.
+func (f *flagFit) report() {
+	f.logTo(logger.With())
+}
+
+// logTo logs what was changed in the flags to log.
+func (f *flagFit) logTo(log *logger.Entry) {
+	if len(f.removed) > 0 {
+		log.Warn("Ignoring nmap flags %s, chainmap writes its own output files", strings.Join(f.removed, ", "))
+	}
+	if f.caps != nil && f.caps.NeedsPrivilegedFlag() {
+		log.Info("nmap has %s, running it with --privileged", f.caps)
+	}
+	if len(f.unsupported) > 0 {
+		log.Warn("nmap flags %s need root or CAP_NET_RAW and CAP_NET_ADMIN on nmap; scans may fail or degrade. Use -auto-downgrade to rewrite them", strings.Join(f.unsupported, ", "))
+	}
+	for _, c := range f.changes {
+		log.Warn("No raw socket access, downgrading: %s", c)
+	}
+}
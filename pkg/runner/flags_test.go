@@ -0,0 +1,97 @@
+package runner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+)
+
+func TestFitPrivileges(t *testing.T) {
+	const flags = "-sV -sS -T3 -Pn -n --host-timeout 5m"
+	tests := []struct {
+		name        string
+		caps        privileges.Capabilities
+		downgrade   bool
+		want        string
+		changes     int
+		unsupported []string
+	}{
+		{"Root", privileges.Capabilities{Root: true}, true, flags, 0, nil},
+		{"Capabilities", privileges.Capabilities{NetRaw: true, NetAdmin: true}, false, flags + " --privileged", 0, nil},
+		{"Unprivileged", privileges.Capabilities{}, false, flags, 0, []string{"-sS"}},
+		{"Downgrade", privileges.Capabilities{}, true, "-sV -sT -T3 -Pn -n --host-timeout 5m", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes, unsupported := fitPrivileges(strings.Fields(flags), tt.caps, tt.downgrade)
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("fitPrivileges() = %q, want %q", strings.Join(got, " "), tt.want)
+			}
+			if len(changes) != tt.changes {
+				t.Errorf("changes = %v, want %d", changes, tt.changes)
+			}
+			if !reflect.DeepEqual(unsupported, tt.unsupported) {
+				t.Errorf("unsupported = %v, want %v", unsupported, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestPrepareFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   string
+		policy  *core.FlagPolicy
+		want    string
+		removed int
+		wantErr bool
+	}{
+		{"Output flags removed", "-sS -oN out.txt -oA 'my scan' --webxml", nil, "-sS", 3, false},
+		{"Quoted values kept", "-sS --script-args 'http.useragent=Mozilla 5.0'", nil, "-sS --script-args 'http.useragent=Mozilla 5.0'", 0, false},
+		{"Input flag", "-sS -iL hosts.txt", nil, "", 0, true},
+		{"Only output flags", "-oN out.txt", nil, "", 0, true},
+		{"Denied", "-sS -D RND:5", &core.FlagPolicy{Deny: []string{"-D"}}, "", 0, true},
+		{"Unbalanced quotes", "-sS '", nil, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(&options.Options{NmapFlags: tt.flags})
+			r.policy = tt.policy
+			fit, err := r.prepareFlags(&privileges.Capabilities{Root: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prepareFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := r.nmapFlags(); got != tt.want {
+				t.Errorf("nmapFlags() = %q, want %q", got, tt.want)
+			}
+			if len(fit.removed) != tt.removed {
+				t.Errorf("removed = %v, want %d", fit.removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestStatsEvery(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{10 * time.Second, "10000ms"},
+		{time.Minute, "60000ms"},
+		{1500 * time.Millisecond, "1500ms"},
+		{time.Millisecond, "1ms"},
+	}
+	for _, tt := range tests {
+		if got := statsEvery(tt.d); got != tt.want {
+			t.Errorf("statsEvery(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+// Report merges existing nmap XML files, or directories containing them,
+// and writes the same outputs as a scan without running nmap.
+func (r *Runner) Report(paths []string) error {
+	if len(paths) == 0 {
+		return fmt.Errorf("no XML files or directories given")
+	}
+	if err := r.prepareOutputs(); err != nil {
+		return err
+	}
+
+	xmlFiles, err := collectXMLFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	// Never merge an output into itself when it lives next to the inputs.
+	outputs := make(map[string]bool)
+	for _, out := range r.outputs {
+		if abs, err := filepath.Abs(out.Path); err == nil {
+			outputs[abs] = true
+		}
+	}
+	kept := xmlFiles[:0]
+	for _, f := range xmlFiles {
+		if abs, err := filepath.Abs(f); err == nil && outputs[abs] {
+			logger.Warn("Skipping %s: it is an output file", f)
+			continue
+		}
+		kept = append(kept, f)
+	}
+	xmlFiles = kept
+	if len(xmlFiles) == 0 {
+		return fmt.Errorf("no XML files found")
+	}
+
+	logger.Info("Importing %d nmap XML files", len(xmlFiles))
+	now := time.Now()
+	return r.writeOutputs(xmlFiles, &core.Manifest{Started: now, Finished: now})
+}
+
+// collectXMLFiles expands directories into the .xml files they contain.
+func collectXMLFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".xml") {
+				found = append(found, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(found)
+		files = append(files, found...)
+	}
+	return files, nil
+}
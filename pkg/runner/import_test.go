@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+const importXML = `<?xml version="1.0"?>
+<nmaprun scanner="nmap" args="nmap" version="7.94">
+<host><status state="up"/><address addr="10.0.0.1" addrtype="ipv4"/>
+<ports><port protocol="tcp" portid="22"><state state="open"/><service name="ssh"/></port></ports>
+</host>
+<runstats><finished elapsed="1"/><hosts up="1" down="0" total="1"/></runstats>
+</nmaprun>
+`
+
+func TestCollectXMLFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.xml", "a.XML", "notes.txt", "sub/c.xml"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(importXML), 0644)
+	}
+	single := filepath.Join(dir, "notes.txt")
+
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		{"Directory", []string{dir}, []string{"a.XML", "b.xml", "sub/c.xml"}, false},
+		{"File kept as given", []string{single}, []string{"notes.txt"}, false},
+		{"Mixed", []string{filepath.Join(dir, "sub"), single}, []string{"sub/c.xml", "notes.txt"}, false},
+		{"Missing", []string{filepath.Join(dir, "missing")}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := collectXMLFiles(tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("collectXMLFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, f := range files {
+				rel, _ := filepath.Rel(dir, f)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectXMLFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.xml")
+	bad := filepath.Join(dir, "bad.xml")
+	os.WriteFile(good, []byte(importXML), 0644)
+	os.WriteFile(bad, []byte("not xml"), 0644)
+
+	jsonOut := filepath.Join(dir, "out.json")
+	tests := []struct {
+		name    string
+		paths   []string
+		output  string
+		wantErr bool
+	}{
+		{"Merged", []string{good, bad}, jsonOut, false},
+		{"Nothing to merge", []string{bad}, jsonOut, true},
+		{"Only the output", []string{good}, good, true},
+		{"No paths", nil, jsonOut, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(jsonOut)
+			opts := &options.Options{Outputs: []string{tt.output}, Summary: "none"}
+			err := New(opts).Report(tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Report() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(jsonOut); (err == nil) != (tt.output == jsonOut && !tt.wantErr) {
+				t.Errorf("out.json written = %v", err == nil)
+			}
+		})
+	}
+
+	// The merged output is not read back as an input.
+	out := filepath.Join(dir, "merged.xml")
+	if err := New(&options.Options{Outputs: []string{out}, Summary: "none"}).Report([]string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(&options.Options{Outputs: []string{out}, Summary: "none"}).Report([]string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	run, err := core.ParseXML(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Hosts) != 1 {
+		t.Errorf("merged %d hosts, want 1", len(run.Hosts))
+	}
+}
@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+// stderrTailLines is how much nmap stderr is kept for failed jobs.
+const stderrTailLines = 5
+
+func (r *Runner) addRecord(record core.JobRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record)
+}
+
+// takeRecord removes and returns the record of job id.
+func (r *Runner) takeRecord(id int) (core.JobRecord, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rec := range r.records {
+		if rec.ID == id {
+			r.records = append(r.records[:i], r.records[i+1:]...)
+			return rec, true
+		}
+	}
+	return core.JobRecord{}, false
+}
+
+// jobRecords returns the finished jobs ordered by job ID.
+func (r *Runner) jobRecords() []core.JobRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := append([]core.JobRecord{}, r.records...)
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+// reportFailures logs every failed or timed-out job with the tail of its
+// stderr, and how many jobs the budget left unscanned.
+func reportFailures(records []core.JobRecord) {
+	var failed []core.JobRecord
+	skipped := 0
+	for _, rec := range records {
+		switch {
+		case rec.Status == scanSkipped.String():
+			skipped++
+		case rec.Failed():
+			failed = append(failed, rec)
+		}
+	}
+	if skipped > 0 {
+		logger.Warn("%d of %d jobs not scanned before the budget ran out", skipped, len(records))
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	logger.Warn("%d of %d jobs did not complete", len(failed), len(records))
+	for _, rec := range failed {
+		log := logger.With("host", rec.Host, "job", rec.ID)
+		log.Warn("%s %s (exit code %d, log %s)", rec.Host, rec.Status, rec.ExitCode, rec.StderrLog)
+		for _, line := range rec.StderrTail {
+			log.Warn("  %s", line)
+		}
+	}
+}
+
+// safeName turns a host into something usable as a file name.
+func safeName(host string) string {
+	name := strings.ReplaceAll(host, ".", "_")
+	name = strings.ReplaceAll(name, ":", "_") // Handle ipv6 if needed
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+// tailLines returns the last n non-empty lines of the file at path.
+func tailLines(path string, n int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
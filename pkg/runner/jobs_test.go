@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"10.0.0.1", "10_0_0_1"},
+		{"10.0.0.0/24", "10_0_0_0_24"},
+		{"2001:db8::1", "2001_db8__1"},
+		{"scanme.nmap.org", "scanme_nmap_org"},
+	}
+	for _, tt := range tests {
+		if got := safeName(tt.host); got != tt.want {
+			t.Errorf("safeName(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stderr.log")
+	if err := os.WriteFile(path, []byte("one\n\n  two  \nthree\n\nfour\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{"Last lines", 2, []string{"three", "four"}},
+		{"Blank lines skipped and trimmed", 3, []string{"two", "three", "four"}},
+		{"Fewer lines than n", 10, []string{"one", "two", "three", "four"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tailLines(path, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tailLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := tailLines(filepath.Join(t.TempDir(), "missing.log"), 5); err == nil {
+		t.Error("tailLines() read a missing file")
+	}
+}
+
+func TestReportFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []core.JobRecord
+		want    []string
+	}{
+		{"All ok", []core.JobRecord{{ID: 1, Host: "10.0.0.1", Status: "ok"}}, nil},
+		{
+			"Failed and timed out",
+			[]core.JobRecord{
+				{ID: 1, Host: "10.0.0.1", Status: "ok"},
+				{ID: 2, Host: "10.0.0.2", Status: "failed", ExitCode: 1, StderrLog: "2.log", StderrTail: []string{"QUITTING!"}},
+				{ID: 3, Host: "10.0.0.3", Status: "timeout", ExitCode: -1, StderrLog: "3.log"},
+			},
+			[]string{
+				"2 of 3 jobs did not complete",
+				"10.0.0.2 failed (exit code 1, log 2.log)",
+				"  QUITTING!",
+				"10.0.0.3 timeout (exit code -1, log 3.log)",
+			},
+		},
+		{
+			"Skipped by the budget",
+			[]core.JobRecord{
+				{ID: 1, Host: "10.0.0.1", Status: "ok"},
+				{ID: 2, Host: "10.0.0.2", Status: "not_scanned"},
+			},
+			[]string{"1 of 2 jobs not scanned before the budget ran out"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger.SetOutput(&buf)
+			defer logger.SetOutput(os.Stderr)
+
+			reportFailures(tt.records)
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+				if line != "" {
+					got = append(got, line)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("reportFailures() logged %q, want %q", got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("line %d = %q, want it to contain %q", i, got[i], want)
+				}
+			}
+		})
+	}
+}
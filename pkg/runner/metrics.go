@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/pkg/metrics"
+	"github.com/lair-framework/go-nmap"
+)
+
+var (
+	jobsQueued    = metrics.Default.NewGauge("chainmap_jobs_queued", "Jobs waiting for a free worker.")
+	jobsRunning   = metrics.Default.NewGauge("chainmap_jobs_running", "Jobs currently being scanned.")
+	jobsCompleted = metrics.Default.NewCounter("chainmap_jobs_completed_total", "Finished jobs by status (ok, down, timeout, failed, not_scanned).", "status")
+	jobsFailed    = metrics.Default.NewCounter("chainmap_jobs_failed_total", "Jobs that failed or timed out.")
+	jobTimeouts   = metrics.Default.NewCounter("chainmap_job_timeouts_total", "Jobs stopped by -timeout.")
+	scanDuration  = metrics.Default.NewHistogram("chainmap_scan_duration_seconds", "Wall-clock duration of finished jobs.",
+		[]float64{5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600})
+	nmapExitCodes = metrics.Default.NewCounter("chainmap_nmap_exit_codes_total", "Exit codes of nmap runs (-1 when nmap was killed or never started).", "code")
+	openPorts     = metrics.Default.NewCounter("chainmap_open_ports_total", "Open ports found, by detected service.", "service")
+	concurrency   = metrics.Default.NewGauge("chainmap_concurrency", "Current worker concurrency limit.")
+)
+
+// observeJob records a finished job in the metrics.
+func observeJob(record core.JobRecord, result *nmap.NmapRun) {
+	jobsCompleted.Inc(record.Status)
+	if record.Status == scanSkipped.String() {
+		return
+	}
+	if record.Failed() {
+		jobsFailed.Inc()
+	}
+	if record.Status == scanTimeout.String() {
+		jobTimeouts.Inc()
+	}
+	scanDuration.Observe(record.Duration)
+	if len(record.Command) > 0 {
+		nmapExitCodes.Inc(strconv.Itoa(record.ExitCode))
+	}
+	if result == nil {
+		return
+	}
+	for _, host := range result.Hosts {
+		for _, port := range host.Ports {
+			if port.State.State != "open" {
+				continue
+			}
+			service := port.Service.Name
+			if service == "" {
+				service = "unknown"
+			}
+			openPorts.Inc(service)
+		}
+	}
+}
+
+// serveMetrics exposes the metrics on addr under /metrics until the
+// returned function is called.
+func serveMetrics(addr string) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Default.Handler())
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	logger.Info("Serving metrics on http://%s/metrics", ln.Addr())
+	return func() { srv.Close() }, nil
+}
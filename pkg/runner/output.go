@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/report"
+	"github.com/ihsanlearn/chainmap/pkg/store"
+	"github.com/lair-framework/go-nmap"
+)
+
+// writeOutputs merges the per-host XML files and writes every -o output
+// from the merged results. It returns an error when none of the files can
+// be merged or a vulnerability reaches the -fail-cvss threshold.
+func (r *Runner) writeOutputs(xmlFiles []string, manifest *core.Manifest) error {
+	logger.Info("Merging %d scan results", len(xmlFiles))
+	run, err := core.MergeRuns(xmlFiles)
+	if err != nil {
+		return fmt.Errorf("failed to merge XML results: %w", err)
+	}
+
+	// Filtering happens once so that every output shows the same view.
+	if r.filter != nil {
+		run = r.filter.Apply(run)
+		logger.Info("Filter %q kept %d hosts", r.filter.String(), len(run.Hosts))
+	}
+	vulns := core.ExtractVulnerabilities(run)
+
+	r.printSummary(run, vulns)
+
+	if r.options.Database != "" {
+		r.saveToDatabase(run, manifest)
+	}
+
+	res := r.results(run, vulns)
+	for _, out := range r.outputs {
+		if err := out.Write(res); err != nil {
+			logger.Error("Failed to write %s report %s: %s", out.Format.Title, out.Path, err)
+			continue
+		}
+		logger.Success("%s report saved to %s", out.Format.Title, out.Path)
+	}
+
+	return checkThreshold(vulns, r.options.FailCVSS)
+}
+
+// results bundles the merged run for the output writers.
+func (r *Runner) results(run *nmap.NmapRun, vulns []core.Vulnerability) *report.Results {
+	return &report.Results{
+		Run:        run,
+		Vulns:      vulns,
+		Commands:   r.commands(),
+		Downgraded: core.FlagChanges(r.jobRecords()),
+	}
+}
+
+// WriteReport renders merged results from xmlPath into path, whose format
+// is chosen like an -o value, using the report options in opts. The job
+// manifest in opts.StateDir, when present, supplies the nmap commands.
+func WriteReport(opts *options.Options, xmlPath, path string) error {
+	r := New(opts)
+	if err := r.prepareOutputs(); err != nil {
+		return err
+	}
+	out, err := report.ParseOutput(path, r.outputDefaults())
+	if err != nil {
+		return err
+	}
+	if m, err := core.ReadManifest(filepath.Join(opts.StateDir, "manifest.json")); err == nil {
+		r.records = m.Jobs
+	}
+
+	run, err := core.ParseXML(xmlPath)
+	if err != nil {
+		return err
+	}
+	if r.filter != nil {
+		run = r.filter.Apply(run)
+	}
+	return out.Write(r.results(run, core.ExtractVulnerabilities(run)))
+}
+
+// printSummary prints the -summary views, or writes them as JSON to
+// -summary-json. With "-summary-json -" the JSON replaces the text summary
+// on stdout.
+func (r *Runner) printSummary(run *nmap.NmapRun, vulns []core.Vulnerability) {
+	summary := report.BuildSummary(run, vulns, r.jobRecords(), r.summaryViews)
+
+	switch r.options.SummaryJSON {
+	case "":
+		report.PrintSummary(summary, r.summaryViews)
+	case "-":
+		if err := report.WriteSummaryJSON(summary, os.Stdout); err != nil {
+			logger.Error("Failed to write summary: %s", err)
+		}
+	default:
+		report.PrintSummary(summary, r.summaryViews)
+		f, err := os.Create(r.options.SummaryJSON)
+		if err != nil {
+			logger.Error("Failed to write summary: %s", err)
+			return
+		}
+		defer f.Close()
+		if err := report.WriteSummaryJSON(summary, f); err != nil {
+			logger.Error("Failed to write summary: %s", err)
+			return
+		}
+		logger.Success("Summary saved to %s", r.options.SummaryJSON)
+	}
+}
+
+// saveToDatabase appends the merged results to the -db results database as
+// a new scan.
+func (r *Runner) saveToDatabase(run *nmap.NmapRun, manifest *core.Manifest) {
+	db, err := store.Open(r.options.Database)
+	if err != nil {
+		logger.Error("Failed to open results database: %s", err)
+		return
+	}
+	defer db.Close()
+
+	var label string
+	if len(r.outputs) > 0 {
+		label = r.outputs[0].Path
+	}
+	scanID, err := db.SaveScan(run, label, manifest.Started, manifest.Finished)
+	if err != nil {
+		logger.Error("Failed to save results to database: %s", err)
+		return
+	}
+	logger.Success("Results saved to %s as scan #%d", r.options.Database, scanID)
+}
+
+// commands returns the shell-quoted nmap command of every job that ran.
+func (r *Runner) commands() []string {
+	var cmds []string
+	for _, rec := range r.jobRecords() {
+		if len(rec.Command) > 0 {
+			cmds = append(cmds, shellJoin(rec.Command))
+		}
+		if len(rec.ScriptCommand) > 0 {
+			cmds = append(cmds, shellJoin(rec.ScriptCommand))
+		}
+	}
+	return cmds
+}
+
+// checkThreshold fails the run when any vulnerability scores at or above
+// threshold. A threshold of zero disables the check.
+func checkThreshold(vulns []core.Vulnerability, threshold float64) error {
+	if threshold <= 0 {
+		return nil
+	}
+	var hits int
+	for _, v := range vulns {
+		if v.CVSS >= threshold {
+			hits++
+		}
+	}
+	if hits > 0 {
+		return fmt.Errorf("%d vulnerabilities at or above CVSS %.1f", hits, threshold)
+	}
+	return nil
+}
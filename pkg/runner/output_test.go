@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/internal/nmaptest"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func TestPrepareOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []string
+		columns string
+		want    []string
+		wantErr bool
+	}{
+		{"Extensions", []string{"results.xml", "results.json", "findings.sarif"}, "", []string{"xml results.xml", "json results.json", "sarif findings.sarif"}, false},
+		{"Format prefix", []string{"json:scan.out", "md:notes.txt"}, "", []string{"json scan.out", "md notes.txt"}, false},
+		{"Options", []string{"hosts.csv?columns=ip,port", "compact.json?indent=false"}, "", []string{"csv hosts.csv", "json compact.json"}, false},
+		{"Default columns", []string{"hosts.csv"}, "ip,port", []string{"csv hosts.csv"}, false},
+		{"Unknown extension", []string{"results.txt"}, "", nil, true},
+		{"Unknown option", []string{"results.json?columns=ip"}, "", nil, true},
+		{"Invalid columns", []string{"hosts.csv?columns=nope"}, "", nil, true},
+		{"Invalid default columns", []string{"hosts.csv"}, "nope", nil, true},
+		{"Duplicate path", []string{"results.xml", "xml:results.xml"}, "", nil, true},
+		{"No file name", []string{"json:"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(&options.Options{Outputs: tt.outputs, Columns: tt.columns})
+			err := r.prepareOutputs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("prepareOutputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, out := range r.outputs {
+				got = append(got, out.Format.Name+" "+out.Path)
+			}
+			if strings.Join(got, ";") != strings.Join(tt.want, ";") {
+				t.Errorf("outputs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteOutputs(t *testing.T) {
+	nmaptest.Install(t)
+	t.Setenv("FAKE_SLEEP", "0")
+	dir := t.TempDir()
+	opts := &options.Options{
+		Targets:    []string{"10.0.0.1", "10.0.0.2"},
+		Threads:    2,
+		MinThreads: 1,
+		Timeout:    10,
+		Outputs: []string{
+			filepath.Join(dir, "results.xml"),
+			filepath.Join(dir, "results.json"),
+			filepath.Join(dir, "findings.sarif"),
+			filepath.Join(dir, "hosts.csv") + "?columns=ip,port,service",
+			"md:" + filepath.Join(dir, "report.txt"),
+		},
+		StateDir:   filepath.Join(dir, "state"),
+		Summary:    "none",
+		NoProgress: true,
+	}
+	if err := New(opts).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"results.xml", "results.json", "findings.sarif", "hosts.csv", "report.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+	// Only the requested outputs are written.
+	if _, err := os.Stat(filepath.Join(dir, "results.html")); err == nil {
+		t.Error("results.html written without being requested")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ip,port,service\n10.0.0.1,22,ssh\n10.0.0.2,22,ssh\n"
+	if string(data) != want {
+		t.Errorf("hosts.csv = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,209 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/pkg/report"
+)
+
+// Files of an -output-dir tree. Every job gets a folder below hostsDir.
+const (
+	hostsDir     = "hosts"
+	hostXML      = "nmap.xml"
+	hostGrepable = "nmap.gnmap"
+	hostLog      = "nmap.log"
+	hostErrLog   = "nmap.stderr.log"
+	urlsFile     = "urls.txt"
+	manifestFile = "manifest.json"
+	indexFile    = "index.html"
+)
+
+// prepareOutputDir creates the -output-dir tree and moves relative -o
+// outputs into it. The tree always has the merged results as XML and JSON,
+// and as HTML when xsltproc is installed to render it.
+func (r *Runner) prepareOutputDir() error {
+	dir := r.options.OutputDir
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(dir, hostsDir), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	have := make(map[string]bool)
+	for _, out := range r.outputs {
+		if !filepath.IsAbs(out.Path) {
+			out.Path = filepath.Join(dir, out.Path)
+		}
+		have[out.Format.Name] = true
+	}
+	forced := []string{"xml", "json"}
+	if _, err := exec.LookPath("xsltproc"); err == nil {
+		forced = append(forced, "html")
+	}
+	for _, name := range forced {
+		if have[name] {
+			continue
+		}
+		out, err := report.ParseOutput(name+":"+filepath.Join(dir, "results."+name), r.outputDefaults())
+		if err != nil {
+			return err
+		}
+		r.outputs = append(r.outputs, out)
+	}
+	return nil
+}
+
+// writeOutputDir completes the -output-dir tree once the merged reports are
+// written. Each job gets a folder with its raw XML from scanDir, grepable
+// output, nmap log and web URLs; the tree gets the run manifest and an
+// index.html linking everything.
+func (r *Runner) writeOutputDir(scanDir string, manifest *core.Manifest) {
+	dir := r.options.OutputDir
+	index := &report.Index{Generated: time.Now(), Command: shellJoin(os.Args)}
+
+	var allURLs []string
+	for i := range manifest.Jobs {
+		rec := &manifest.Jobs[i]
+		if rec.Status == scanSkipped.String() {
+			continue
+		}
+		host, urls, err := writeHostArtifacts(dir, scanDir, rec)
+		if err != nil {
+			logger.Error("Failed to write artifacts of %s: %s", rec.Host, err)
+			continue
+		}
+		index.Hosts = append(index.Hosts, host)
+		allURLs = append(allURLs, urls...)
+	}
+	if len(allURLs) > 0 {
+		sort.Strings(allURLs)
+		if err := writeLines(filepath.Join(dir, urlsFile), allURLs); err != nil {
+			logger.Error("Failed to write URL list: %s", err)
+		}
+	}
+
+	files, err := treeFiles(dir)
+	if err != nil {
+		logger.Error("Failed to list output directory: %s", err)
+	}
+	index.Files = files
+
+	out := *manifest
+	out.Command = os.Args
+	out.Files = append(append([]string{}, files...), manifestFile, indexFile)
+	if err := core.WriteManifest(filepath.Join(dir, manifestFile), &out); err != nil {
+		logger.Error("Failed to write run manifest: %s", err)
+	}
+	if err := report.WriteIndex(index, filepath.Join(dir, indexFile)); err != nil {
+		logger.Error("Failed to write index: %s", err)
+		return
+	}
+	logger.Success("Output tree saved to %s", filepath.Join(dir, indexFile))
+}
+
+// writeHostArtifacts fills the folder of the job in rec and records it in
+// rec.Artifacts. It returns the index entry and the web URLs of the job.
+func writeHostArtifacts(dir, scanDir string, rec *core.JobRecord) (report.IndexHost, []string, error) {
+	rel := path.Join(hostsDir, hostDirName(rec.Host))
+	target := filepath.Join(dir, filepath.FromSlash(rel))
+	host := report.IndexHost{Target: rec.Host, Status: rec.Status, Dir: rel}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return host, nil, err
+	}
+	rec.Artifacts = rel
+
+	var urls []string
+	xmlPath := filepath.Join(scanDir, safeName(rec.Host)+".xml")
+	if run, err := core.ParseXML(xmlPath); err == nil {
+		if err := copyFile(xmlPath, filepath.Join(target, hostXML)); err != nil {
+			return host, nil, err
+		}
+		host.Files = append(host.Files, hostXML)
+		if err := core.WriteGrepable(run, filepath.Join(target, hostGrepable)); err != nil {
+			return host, nil, err
+		}
+		host.Files = append(host.Files, hostGrepable)
+
+		if urls = core.WebURLs(run); len(urls) > 0 {
+			if err := writeLines(filepath.Join(target, urlsFile), urls); err != nil {
+				return host, nil, err
+			}
+			host.Files = append(host.Files, urlsFile)
+		}
+		for _, h := range run.Hosts {
+			for _, p := range h.Ports {
+				if p.State.State == "open" {
+					host.OpenPorts++
+				}
+			}
+		}
+	}
+
+	// Workers keep their logs, so coordinated jobs have none here.
+	if copyFile(rec.StdoutLog, filepath.Join(target, hostLog)) == nil {
+		host.Files = append(host.Files, hostLog)
+	}
+	if info, err := os.Stat(rec.StderrLog); err == nil && info.Size() > 0 {
+		if copyFile(rec.StderrLog, filepath.Join(target, hostErrLog)) == nil {
+			host.Files = append(host.Files, hostErrLog)
+		}
+	}
+	return host, urls, nil
+}
+
+// hostDirName turns a job target into a folder name. Addresses keep their
+// dots, unlike the file names of safeName.
+func hostDirName(host string) string {
+	return strings.NewReplacer("/", "_", ":", "_", `\`, "_").Replace(host)
+}
+
+// treeFiles returns the files at the top of the tree, without the manifest
+// and index that are written last.
+func treeFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && e.Name() != manifestFile && e.Name() != indexFile {
+			files = append(files, e.Name())
+		}
+	}
+	return files, nil
+}
+
+func copyFile(src, dst string) error {
+	if src == "" {
+		return os.ErrNotExist
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func writeLines(path string, lines []string) error {
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
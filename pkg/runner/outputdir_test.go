@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/internal/nmaptest"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func TestOutputDir(t *testing.T) {
+	nmaptest.Install(t)
+	t.Setenv("FAKE_SLEEP", "0")
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	opts := &options.Options{
+		Targets:    []string{"10.0.0.1", "fail.example"},
+		Threads:    2,
+		MinThreads: 1,
+		Timeout:    10,
+		Outputs:    []string{"results.xml"},
+		OutputDir:  out,
+		StateDir:   filepath.Join(dir, "state"),
+		Summary:    "none",
+		NoProgress: true,
+	}
+	if err := New(opts).Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"results.xml", "results.json", "manifest.json", "index.html",
+		"hosts/10.0.0.1/nmap.xml", "hosts/10.0.0.1/nmap.gnmap", "hosts/10.0.0.1/nmap.log",
+		"hosts/fail.example/nmap.log",
+	} {
+		if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+
+	m, err := core.ReadManifest(filepath.Join(out, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var artifacts []string
+	for _, rec := range m.Jobs {
+		artifacts = append(artifacts, rec.Artifacts)
+	}
+	if want := []string{"hosts/10.0.0.1", "hosts/fail.example"}; !reflect.DeepEqual(artifacts, want) {
+		t.Errorf("artifacts = %q, want %q", artifacts, want)
+	}
+	if len(m.Files) == 0 || len(m.Command) == 0 {
+		t.Errorf("manifest files = %q, command = %q", m.Files, m.Command)
+	}
+}
+
+func TestPrepareOutputDir(t *testing.T) {
+	xsltproc := t.TempDir()
+	if err := os.WriteFile(filepath.Join(xsltproc, "xsltproc"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		outputs []string
+		want    []string
+	}{
+		{"Without xsltproc", t.TempDir(), nil, []string{"results.xml", "results.json"}},
+		{"With xsltproc", xsltproc, nil, []string{"results.xml", "results.json", "results.html"}},
+		{"Requested outputs kept", xsltproc, []string{"scan.html", "json:data.out"}, []string{"scan.html", "data.out", "results.xml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PATH", tt.path)
+			dir := t.TempDir()
+			r := New(&options.Options{Outputs: tt.outputs, OutputDir: dir})
+			if err := r.prepareOutputs(); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.prepareOutputDir(); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, out := range r.outputs {
+				rel, _ := filepath.Rel(dir, out.Path)
+				got = append(got, rel)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("outputs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
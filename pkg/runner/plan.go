@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+// planScanDir is where planned commands write their XML output.
+const planScanDir = "chainmap-scans"
+
+// Placeholders in a planned script phase, which depends on the services
+// the service scan finds.
+const (
+	planPorts   = "<open mapped ports>"
+	planScripts = "<mapped scripts>"
+)
+
+// Plan is the list of nmap commands a run would execute.
+type Plan struct {
+	Generated time.Time  `json:"generated"`
+	Jobs      []PlanItem `json:"jobs"`
+}
+
+// PlanItem is one planned nmap job.
+type PlanItem struct {
+	ID      int      `json:"id"`
+	Host    string   `json:"host"`
+	Ports   []string `json:"ports,omitempty"`
+	Command []string `json:"command"`
+	// ScriptCommand is the -auto-scripts phase, which only runs when
+	// services with mapped scripts are open. Its ports and scripts are
+	// placeholders, and -sU is added when UDP ports are among them.
+	ScriptCommand []string `json:"script_command,omitempty"`
+	Timeout       string   `json:"timeout"`
+}
+
+// dryRun prints the command every job would run and optionally exports the plan.
+func (r *Runner) dryRun(jobs []*job) error {
+	plan, err := r.plan(jobs)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Dry run: %d jobs planned, nothing will be executed", len(plan.Jobs))
+	for _, item := range plan.Jobs {
+		fmt.Println(shellJoin(item.Command))
+		if item.ScriptCommand != nil {
+			fmt.Println("  then, if services with mapped scripts are open: " + shellJoin(item.ScriptCommand))
+		}
+	}
+
+	if r.options.PlanOutput == "" {
+		return nil
+	}
+	if err := writePlan(plan, r.options.PlanOutput); err != nil {
+		return err
+	}
+	logger.Success("Scan plan saved to %s", r.options.PlanOutput)
+	return nil
+}
+
+// plan builds the commands of jobs the way scanTarget does. Workers add
+// neither the progress stats nor the coordinator's sandbox.
+func (r *Runner) plan(jobs []*job) (*Plan, error) {
+	plan := &Plan{Generated: time.Now()}
+	stats := r.showProgress() && r.options.Coordinator == ""
+	for _, j := range jobs {
+		outputFile := filepath.Join(planScanDir, safeName(j.Host)+".xml")
+		argv, err := r.jobCommand(j, outputFile, stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse nmap flags: %w", err)
+		}
+		timeout, _ := r.jobTimeout(j)
+		item := PlanItem{
+			ID:      j.ID,
+			Host:    j.Host,
+			Ports:   j.Ports,
+			Command: argv,
+			Timeout: timeout.String(),
+		}
+		if r.scriptMap != nil {
+			item.ScriptCommand, _ = r.scriptCommand(j, []string{planPorts}, []string{planScripts}, scriptOutput(outputFile))
+		}
+		plan.Jobs = append(plan.Jobs, item)
+	}
+	return plan, nil
+}
+
+// writePlan exports plan as JSON or a shell script, chosen by extension.
+func writePlan(plan *Plan, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	case ".sh":
+		var sb strings.Builder
+		sb.WriteString("#!/bin/sh\n")
+		fmt.Fprintf(&sb, "# Chainmap scan plan generated %s\n", plan.Generated.Format(time.RFC3339))
+		fmt.Fprintf(&sb, "# %d jobs\n\n", len(plan.Jobs))
+		fmt.Fprintf(&sb, "mkdir -p %s\n\n", planScanDir)
+		for _, item := range plan.Jobs {
+			fmt.Fprintf(&sb, "# job %d: %s (timeout %s)\n", item.ID, item.Host, item.Timeout)
+			sb.WriteString(shellJoin(item.Command) + "\n")
+			if item.ScriptCommand != nil {
+				sb.WriteString("# then, if services with mapped scripts are open (-sU is added for UDP ports):\n")
+				sb.WriteString("# " + shellJoin(item.ScriptCommand) + "\n")
+			}
+		}
+		return os.WriteFile(path, []byte(sb.String()), 0755)
+	default:
+		return fmt.Errorf("unsupported plan format %q (use .sh or .json)", filepath.Ext(path))
+	}
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellJoin quotes args so the result can be pasted into a POSIX shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if shellSafe.MatchString(arg) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"nmap", "-sV", "-p", "22,80", "10.0.0.0/24"}, "nmap -sV -p 22,80 10.0.0.0/24"},
+		{[]string{"--script", "default and not intrusive"}, "--script 'default and not intrusive'"},
+		{[]string{"--script-args", "user='admin'"}, `--script-args 'user='\''admin'\'''`},
+		{[]string{"-oX", "out dir/$HOME.xml"}, `-oX 'out dir/$HOME.xml'`},
+		{[]string{""}, "''"},
+	}
+	for _, tt := range tests {
+		if got := shellJoin(tt.args); got != tt.want {
+			t.Errorf("shellJoin(%q) = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	dir := t.TempDir()
+	plan := &Plan{
+		Generated: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Jobs: []PlanItem{{
+			ID:            1,
+			Host:          "10.0.0.1",
+			Command:       []string{"nmap", "-sV", "10.0.0.1"},
+			ScriptCommand: []string{"nmap", "--script", planScripts, "10.0.0.1"},
+			Timeout:       "10m0s",
+		}},
+	}
+
+	sh := filepath.Join(dir, "plan.sh")
+	if err := writePlan(plan, sh); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(sh)
+	for _, want := range []string{
+		"#!/bin/sh\n", "# 1 jobs\n", "mkdir -p chainmap-scans\n",
+		"# job 1: 10.0.0.1 (timeout 10m0s)\nnmap -sV 10.0.0.1\n",
+		"# nmap --script '<mapped scripts>' 10.0.0.1\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("plan.sh lacks %q:\n%s", want, data)
+		}
+	}
+
+	js := filepath.Join(dir, "plan.json")
+	if err := writePlan(plan, js); err != nil {
+		t.Fatal(err)
+	}
+	var got Plan
+	data, _ = os.ReadFile(js)
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&got, plan) {
+		t.Errorf("plan.json = %+v, want %+v", got, plan)
+	}
+
+	if err := writePlan(plan, filepath.Join(dir, "plan.txt")); err == nil {
+		t.Error("writePlan() accepted a .txt plan")
+	}
+}
+
+// TestPlanMatchesScan checks that -dry-run shows the command scanTarget
+// runs, including the progress stats and the sandbox wrapper.
+func TestPlanMatchesScan(t *testing.T) {
+	opts := &options.Options{NmapFlags: "-sV -T4", StatsEvery: 10 * time.Second, AutoScripts: true}
+	r := New(opts)
+	r.sandboxExe = "/usr/bin/chainmap"
+	r.scriptMap = core.DefaultScriptMap
+	j := &job{ID: 1, Host: "10.0.0.1", Ports: []string{"22"}, log: logger.With("host", "10.0.0.1")}
+
+	plan, err := r.plan([]*job{j})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := plan.Jobs[0]
+	outputFile := filepath.Join(planScanDir, "10_0_0_1.xml")
+	want, _ := r.jobCommand(j, outputFile, true)
+	if !reflect.DeepEqual(item.Command, want) {
+		t.Errorf("planned %q, scan runs %q", item.Command, want)
+	}
+	wantCmd := "/usr/bin/chainmap sandbox-exec chainmap-scans -- nmap -sV -T4 -oX chainmap-scans/10_0_0_1.xml --webxml -p 22 --stats-every 10000ms 10.0.0.1"
+	if got := shellJoin(item.Command); got != wantCmd {
+		t.Errorf("command = %s, want %s", got, wantCmd)
+	}
+	if want, _ := r.scriptCommand(j, []string{planPorts}, []string{planScripts}, filepath.Join(planScanDir, "10_0_0_1.scripts")); !reflect.DeepEqual(item.ScriptCommand, want) {
+		t.Errorf("script command = %q, want %q", item.ScriptCommand, want)
+	}
+
+	opts.NoProgress = true
+	r.scriptMap = nil
+	plan, _ = r.plan([]*job{j})
+	if strings.Contains(shellJoin(plan.Jobs[0].Command), "--stats-every") || plan.Jobs[0].ScriptCommand != nil {
+		t.Errorf("plan without progress and scripts = %+v", plan.Jobs[0])
+	}
+}
@@ -0,0 +1,256 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/pkg/queue"
+	"github.com/ihsanlearn/chainmap/pkg/report"
+	"github.com/ihsanlearn/chainmap/pkg/store"
+	"github.com/lair-framework/go-nmap"
+)
+
+// QueueResult is published to -queue-out for every finished job.
+type QueueResult struct {
+	Job   core.JobRecord    `json:"job"`
+	Ports []report.OpenPort `json:"ports"`
+	XML   string            `json:"xml,omitempty"`
+}
+
+// queueBatch tracks the jobs of one queue message. The message is
+// acknowledged once all of them finished, or handed back to the queue when
+// any was interrupted by shutdown.
+type queueBatch struct {
+	msg       *queue.Message
+	mu        sync.Mutex
+	pending   int
+	cancelled bool
+}
+
+func (b *queueBatch) done(cancelled bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending--
+	b.cancelled = b.cancelled || cancelled
+	if b.pending > 0 {
+		return
+	}
+	var err error
+	if b.cancelled {
+		err = b.msg.Nack()
+	} else {
+		err = b.msg.Ack()
+	}
+	if err != nil {
+		logger.Warn("Failed to settle queue message: %s", err)
+	}
+}
+
+type queueJob struct {
+	job   *job
+	batch *queueBatch
+}
+
+// runQueue scans targets taken from -queue-in until ctx is cancelled or the
+// -budget runs out. Each
+// message holds target lines in the same format as stdin; every finished job
+// is published to -queue-out and saved to -db.
+func (r *Runner) runQueue(ctx context.Context) error {
+	in, err := queue.Open(r.options.QueueIn)
+	if err != nil {
+		return fmt.Errorf("could not open input queue: %w", err)
+	}
+	defer in.Close()
+
+	var out queue.Queue
+	if r.options.QueueOut != "" {
+		if out, err = queue.Open(r.options.QueueOut); err != nil {
+			return fmt.Errorf("could not open output queue: %w", err)
+		}
+		defer out.Close()
+	}
+
+	var db *store.DB
+	if r.options.Database != "" {
+		if db, err = store.Open(r.options.Database); err != nil {
+			return fmt.Errorf("failed to open results database: %w", err)
+		}
+		defer db.Close()
+	}
+
+	tempDir, err := os.MkdirTemp("", "chainmap-queue")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	r.logDir = filepath.Join(r.options.StateDir, "logs")
+	if err := os.MkdirAll(r.logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	minThreads := r.options.Threads
+	if r.options.Adaptive {
+		minThreads = r.options.MinThreads
+	}
+	r.limiter = newConcurrencyLimiter(minThreads, r.options.Threads)
+	concurrency.Add(float64(r.limiter.current()))
+	defer func() { concurrency.Add(-float64(r.limiter.current())) }()
+
+	jobs := make(chan queueJob)
+	var wg sync.WaitGroup
+	for i := 0; i < r.limiter.max; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			// Workers share host names across messages, so each gets its
+			// own directory for nmap output.
+			dir := filepath.Join(tempDir, fmt.Sprint(worker))
+			os.MkdirAll(dir, 0755)
+			for {
+				r.limiter.acquire()
+				qj, ok := <-jobs
+				if !ok {
+					r.limiter.release()
+					return
+				}
+				jobsQueued.Add(-1)
+				timeout, ok := r.jobTimeout(qj.job)
+				if !ok {
+					r.limiter.release()
+					qj.batch.done(true)
+					continue
+				}
+				status := r.scanTarget(ctx, worker, qj.job, dir, timeout)
+				r.limiter.record(status)
+				if ctx.Err() != nil {
+					r.takeRecord(qj.job.ID)
+					qj.batch.done(true)
+					continue
+				}
+				r.publish(out, db, qj.job, dir)
+				qj.batch.done(false)
+			}
+		}(i)
+	}
+
+	// With a -budget the consumer stops taking messages at the deadline and
+	// lets the running jobs finish within it.
+	popCtx := ctx
+	if !r.deadline.IsZero() {
+		var cancel context.CancelFunc
+		popCtx, cancel = context.WithDeadline(ctx, r.deadline)
+		defer cancel()
+	}
+
+	logger.Info("Waiting for targets on %s", r.options.QueueIn)
+	nextID := 0
+	for popCtx.Err() == nil {
+		msg, err := in.Pop(popCtx)
+		if err != nil {
+			if popCtx.Err() == nil {
+				logger.Error("Failed to read from input queue: %s", err)
+				select {
+				case <-popCtx.Done():
+				case <-time.After(time.Second):
+				}
+			}
+			continue
+		}
+
+		var lines []string
+		for _, line := range strings.Split(string(msg.Body), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		batchJobs, err := r.planJobs(lines)
+		if err != nil {
+			logger.Error("Invalid queue message, dropping it: %s", err)
+			msg.Ack()
+			continue
+		}
+		if len(batchJobs) == 0 {
+			logger.Warn("Queue message without targets, skipping")
+			msg.Ack()
+			continue
+		}
+
+		batch := &queueBatch{msg: msg, pending: len(batchJobs)}
+		jobsQueued.Add(float64(len(batchJobs)))
+		for _, j := range batchJobs {
+			nextID++
+			j.ID = nextID
+			j.log = logger.With("host", j.Host, "job", j.ID)
+			select {
+			case jobs <- queueJob{job: j, batch: batch}:
+			case <-popCtx.Done():
+				jobsQueued.Add(-1)
+				batch.done(true)
+			}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+	logger.Info("Queue consumer stopped")
+	return nil
+}
+
+// publish sends the result of j to the output queue and the database.
+func (r *Runner) publish(out queue.Queue, db *store.DB, j *job, dir string) {
+	record, _ := r.takeRecord(j.ID)
+	result := QueueResult{Job: record, Ports: []report.OpenPort{}}
+
+	xmlPath := filepath.Join(dir, safeName(j.Host)+".xml")
+	defer os.Remove(xmlPath)
+
+	var run *nmap.NmapRun
+	if !record.Failed() {
+		var err error
+		if run, err = core.ParseXML(xmlPath); err != nil {
+			j.log.Error("Failed to parse scan result for %s: %s", j.Host, err)
+		}
+	}
+	if run != nil {
+		if r.filter != nil {
+			run = r.filter.Apply(run)
+			if err := core.WriteXML(run, xmlPath); err != nil {
+				j.log.Error("Failed to write filtered result for %s: %s", j.Host, err)
+			}
+		}
+		summary := report.BuildSummary(run, nil, nil, []string{"ports"})
+		result.Ports = append(result.Ports, summary.OpenPorts...)
+		if data, err := os.ReadFile(xmlPath); err == nil {
+			result.XML = string(data)
+		}
+		if db != nil {
+			finished := record.Started.Add(time.Duration(record.Duration * float64(time.Second)))
+			if _, err := db.SaveScan(run, "queue", record.Started, finished); err != nil {
+				j.log.Error("Failed to save %s to database: %s", j.Host, err)
+			}
+		}
+	}
+
+	if out == nil {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		j.log.Error("Failed to encode result for %s: %s", j.Host, err)
+		return
+	}
+	if err := out.Push(context.Background(), data); err != nil {
+		j.log.Error("Failed to publish result for %s: %s", j.Host, err)
+		return
+	}
+	j.log.Debug("Published result for %s", j.Host)
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/internal/nmaptest"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func TestRunQueue(t *testing.T) {
+	nmaptest.Install(t)
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	if err := os.MkdirAll(in, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(in, "001"), []byte("10.0.0.1\n10.0.0.2:22\n"), 0644)
+	os.WriteFile(filepath.Join(in, "002"), []byte("10.0.0.3\n"), 0644)
+
+	opts := &options.Options{
+		Threads:    2,
+		MinThreads: 1,
+		Timeout:    1,
+		StateDir:   filepath.Join(dir, "state"),
+		Filter:     "port == 22",
+		QueueIn:    in,
+		QueueOut:   "dir://" + out,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- New(opts).RunContext(ctx) }()
+
+	var results []string
+	deadline := time.Now().Add(10 * time.Second)
+	for len(results) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d results, want 3", len(results))
+		}
+		time.Sleep(20 * time.Millisecond)
+		results, _ = filepath.Glob(filepath.Join(out, "*.json"))
+	}
+	cancel()
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	hosts := map[string]bool{}
+	for _, path := range results {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res QueueResult
+		if err := json.Unmarshal(data, &res); err != nil {
+			t.Fatal(err)
+		}
+		hosts[res.Job.Host] = true
+		if len(res.Ports) != 1 || res.Ports[0].Port != 22 || !strings.Contains(res.XML, res.Job.Host) {
+			t.Errorf("result for %s = %+v", res.Job.Host, res)
+		}
+	}
+	if len(hosts) != 3 {
+		t.Errorf("result hosts = %v", hosts)
+	}
+
+	for _, path := range []string{filepath.Join(in, "00*"), filepath.Join(in, ".processing", "*")} {
+		if left, _ := filepath.Glob(path); len(left) > 0 {
+			t.Errorf("messages not acknowledged: %v", left)
+		}
+	}
+}
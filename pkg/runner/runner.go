@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -15,11 +17,53 @@ import (
 	"github.com/ihsanlearn/chainmap/core"
 	"github.com/ihsanlearn/chainmap/logger"
 	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+	"github.com/ihsanlearn/chainmap/pkg/progress"
 	"github.com/ihsanlearn/chainmap/pkg/report"
+	"github.com/lair-framework/go-nmap"
 )
 
+// job is a single nmap invocation covering one host and its grouped ports.
+type job struct {
+	ID    int
+	Host  string
+	Ports []string
+	// Timeout overrides the computed nmap timeout when set.
+	Timeout time.Duration
+	log     *logger.Entry
+}
+
 type Runner struct {
-	options *options.Options
+	// OnStart is called with the number of jobs once they are planned.
+	OnStart func(total int)
+	// OnJob is called after every job with its record and, when nmap
+	// produced one, its parsed result.
+	OnJob func(record core.JobRecord, result *nmap.NmapRun)
+
+	options      *options.Options
+	limiter      *concurrencyLimiter
+	progress     *progress.Tracker
+	logDir       string
+	outputs      []*report.Output
+	summaryViews []string
+	filter       *core.Filter
+	scriptMap    core.ScriptMap
+	timeouts     map[string]time.Duration
+	// flags are the scan mode flags fitted to nmap's privileges, and
+	// downgraded what -auto-downgrade changed in them.
+	flags      string
+	downgraded []core.FlagChange
+	policy     *core.FlagPolicy
+	// sandboxExe is the chainmap binary wrapping nmap with -sandbox, and
+	// caps the privileges nmap is started with.
+	sandboxExe string
+	caps       privileges.Capabilities
+	// deadline is when the -budget runs out; zero without a budget.
+	deadline   time.Time
+	budgetOnce sync.Once
+
+	mu      sync.Mutex
+	records []core.JobRecord
 }
 
 func New(opts *options.Options) *Runner {
@@ -27,16 +71,69 @@ func New(opts *options.Options) *Runner {
 }
 
 func (r *Runner) CheckDependencies() error {
-	if _, err := exec.LookPath("nmap"); err != nil {
-		return fmt.Errorf("nmap is not installed or not in PATH")
+	if r.options.DryRun {
+		return nil
 	}
-	if _, err := exec.LookPath("xsltproc"); err != nil {
-		logger.Error("xsltproc is not installed. HTML report will NOT be generated.")
+	// A coordinator leaves the scanning to its workers.
+	if _, err := exec.LookPath("nmap"); err != nil && r.options.Coordinator == "" {
+		return fmt.Errorf("nmap is not installed or not in PATH")
 	}
 	return nil
 }
 
-func (r *Runner) Run() {
+func (r *Runner) Run() error {
+	return r.RunContext(context.Background())
+}
+
+// RunContext scans all targets like Run. Cancelling ctx stops running nmap
+// processes and skips queued jobs; outputs are still written for the jobs
+// that finished.
+func (r *Runner) RunContext(ctx context.Context) error {
+	if r.options.Budget > 0 {
+		r.deadline = time.Now().Add(r.options.Budget)
+	}
+	if err := r.prepareOutputs(); err != nil {
+		return err
+	}
+	if err := r.loadTimeouts(); err != nil {
+		return err
+	}
+	if r.options.MetricsAddr != "" {
+		stop, err := serveMetrics(r.options.MetricsAddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	if err := r.loadFlagPolicy(); err != nil {
+		return err
+	}
+	if err := r.loadScriptMap(); err != nil {
+		return err
+	}
+	var caps *privileges.Capabilities
+	// A coordinator's workers check their own privileges and sandbox.
+	if r.options.Coordinator == "" {
+		if err := r.prepareSandbox(); err != nil {
+			return err
+		}
+		detected := detectPrivileges()
+		if r.options.Sandbox {
+			detected = detected.Sandboxed()
+		}
+		caps = &detected
+		r.caps = detected
+	}
+	fit, err := r.prepareFlags(caps)
+	if err != nil {
+		return err
+	}
+	fit.report()
+	if r.options.QueueIn != "" {
+		return r.runQueue(ctx)
+	}
+
 	var rawLines []string
 
 	if r.options.InputList != "" {
@@ -52,7 +149,22 @@ func (r *Runner) Run() {
 		rawLines = append(rawLines, r.options.Target)
 	}
 
-	if hasStdin() {
+	if r.options.Rescan != "" {
+		selectors, err := core.ParseRescanSelectors(r.options.RescanSelect)
+		if err != nil {
+			return err
+		}
+		rescanTargets, err := core.RescanTargets(r.options.Rescan, selectors)
+		if err != nil {
+			return fmt.Errorf("could not read rescan input: %w", err)
+		}
+		logger.Info("Selected %d targets from %s", len(rescanTargets), r.options.Rescan)
+		rawLines = append(rawLines, rescanTargets...)
+	}
+
+	rawLines = append(rawLines, r.options.Targets...)
+
+	if len(r.options.Targets) == 0 && hasStdin() {
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
 			line := strings.TrimSpace(scanner.Text())
@@ -63,174 +175,420 @@ func (r *Runner) Run() {
 	}
 
 	if len(rawLines) == 0 {
-		return
+		return nil
 	}
 
-	targets := core.ParseTargets(rawLines)
-	logger.Info("Found %d unique targets from %d inputs", len(targets), len(rawLines))
+	jobList, err := r.planJobs(rawLines)
+	if err != nil {
+		return fmt.Errorf("invalid targets: %w", err)
+	}
+	logger.Info("Found %d unique targets from %d inputs", len(jobList), len(rawLines))
+
+	if r.OnStart != nil {
+		r.OnStart(len(jobList))
+	}
 
+	if r.options.DryRun {
+		if err := r.dryRun(jobList); err != nil {
+			return fmt.Errorf("failed to export scan plan: %w", err)
+		}
+		return nil
+	}
+
+	if err := r.prepareOutputDir(); err != nil {
+		return err
+	}
 	tempDir, err := os.MkdirTemp("", "chainmap-scans")
 	if err != nil {
-		logger.Error("Failed to create temporary directory: %s", err)
-		return
+		return fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
 
-	jobs := make(chan string, len(targets))
-	var wg sync.WaitGroup
+	r.logDir = filepath.Join(r.options.StateDir, "logs")
+	if err := os.MkdirAll(r.logDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	manifest := &core.Manifest{Started: time.Now()}
 
-	for i := 0; i < r.options.Threads; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for host := range jobs {
-				ports := targets[host]
-				r.scanTarget(host, ports, tempDir)
-			}
-		}()
+	if r.options.Coordinator != "" {
+		if err := r.runCoordinator(ctx, jobList, tempDir); err != nil {
+			return err
+		}
+	} else {
+		r.runLocal(ctx, jobList, tempDir)
 	}
 
-	for host := range targets {
-		jobs <- host
+	if r.progress != nil {
+		r.progress.Stop()
+		logger.SetOutput(os.Stderr)
 	}
-	close(jobs)
 
-	wg.Wait()
+	manifest.Finished = time.Now()
+	manifest.Jobs = r.jobRecords()
+	manifestPath := filepath.Join(r.options.StateDir, "manifest.json")
+	if err := core.WriteManifest(manifestPath, manifest); err != nil {
+		logger.Error("Failed to write job manifest: %s", err)
+	} else {
+		logger.Info("Job manifest saved to %s", manifestPath)
+	}
+	reportFailures(manifest.Jobs)
 
 	xmlFiles, err := filepath.Glob(filepath.Join(tempDir, "*.xml"))
 	if err != nil {
-		logger.Error("Failed to list scan results: %s", err)
-		return
+		return fmt.Errorf("failed to list scan results: %w", err)
 	}
 
-	if len(xmlFiles) > 0 {
-		xmlOutput := r.options.OutputFile
-		htmlOutput := ""
-		generateHTML := false
+	if len(xmlFiles) == 0 {
+		logger.Info("No scan results to merge")
+	} else {
+		err = r.writeOutputs(xmlFiles, manifest)
+	}
+	if r.options.OutputDir != "" {
+		r.writeOutputDir(tempDir, manifest)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
 
-		_, xsltErr := exec.LookPath("xsltproc")
+// runLocal scans jobList with the local worker pool.
+func (r *Runner) runLocal(ctx context.Context, jobList []*job, outputDir string) {
+	jobs := make(chan *job, len(jobList))
+	var wg sync.WaitGroup
 
-		if strings.HasSuffix(strings.ToLower(xmlOutput), ".html") {
-			htmlOutput = xmlOutput
-			xmlOutput = strings.TrimSuffix(xmlOutput, filepath.Ext(xmlOutput)) + ".xml"
-			generateHTML = (xsltErr == nil)
-			if xsltErr != nil {
-				logger.Warn("Output file is .html but xsltproc not found. Falling back to XML output at %s", xmlOutput)
-			}
-		} else {
-			if xsltErr == nil {
-				htmlOutput = strings.TrimSuffix(xmlOutput, filepath.Ext(xmlOutput)) + ".html"
-				generateHTML = true
-			}
+	minThreads := r.options.Threads
+	if r.options.Adaptive {
+		minThreads = r.options.MinThreads
+	}
+	r.limiter = newConcurrencyLimiter(minThreads, r.options.Threads)
+	if r.limiter.adaptive() {
+		logger.Info("Adaptive concurrency enabled (%d-%d workers)", r.limiter.min, r.limiter.max)
+	}
+	concurrency.Add(float64(r.limiter.current()))
+	defer func() { concurrency.Add(-float64(r.limiter.current())) }()
+
+	if r.showProgress() {
+		r.progress = progress.New(len(jobList), r.options.StatsEvery)
+		if r.limiter.adaptive() {
+			r.progress.Concurrency = r.limiter.current
 		}
+		logger.SetOutput(r.progress.Wrap(os.Stderr))
+		r.progress.Start()
+	}
 
-		logger.Info("Merging %d scan results into %s", len(xmlFiles), xmlOutput)
-		if err := core.MergeXMLs(xmlFiles, xmlOutput); err != nil {
-			logger.Error("Failed to merge XML results: %s", err)
-		} else {
-			logger.Success("Merged results saved to %s", xmlOutput)
+	for i := 0; i < r.limiter.max; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for {
+				r.limiter.acquire()
+				j, ok := <-jobs
+				if !ok || ctx.Err() != nil {
+					if ok {
+						jobsQueued.Add(-1)
+					}
+					r.limiter.release()
+					return
+				}
+				jobsQueued.Add(-1)
+				timeout, ok := r.jobTimeout(j)
+				if !ok {
+					r.limiter.release()
+					r.skipJob(j)
+					continue
+				}
+				r.limiter.record(r.scanTarget(ctx, worker, j, outputDir, timeout))
+			}
+		}(i)
+	}
 
-			report.GenerateSummary(xmlOutput)
+	jobsQueued.Add(float64(len(jobList)))
+	for _, j := range jobList {
+		jobs <- j
+	}
+	close(jobs)
 
-			if generateHTML && htmlOutput != "" {
-				logger.Info("Generating HTML report: %s", htmlOutput)
+	wg.Wait()
+	// Jobs skipped after cancellation are no longer queued.
+	for range jobs {
+		jobsQueued.Add(-1)
+	}
+}
 
-				if err := os.WriteFile("nmap.xsl", []byte(core.DefaultXSLT), 0644); err != nil {
-					logger.Warn("Failed to write embedded nmap.xsl, using defaults: %s", err)
-				}
+// loadScriptMap loads the service to script mapping for -auto-scripts.
+func (r *Runner) loadScriptMap() error {
+	if !r.options.AutoScripts {
+		return nil
+	}
+	r.scriptMap = core.DefaultScriptMap
+	if r.options.ScriptMap != "" {
+		m, err := core.LoadScriptMap(r.options.ScriptMap)
+		if err != nil {
+			return fmt.Errorf("could not load script map: %w", err)
+		}
+		r.scriptMap = m
+	}
+	for service, scripts := range r.scriptMap {
+		if err := r.policy.CheckScripts(scripts); err != nil {
+			return fmt.Errorf("script map entry %s: %w", service, err)
+		}
+	}
+	return nil
+}
 
-				cmd := exec.Command("xsltproc", "-o", htmlOutput, "nmap.xsl", xmlOutput)
-				if err := cmd.Run(); err != nil {
-					logger.Error("Failed to generate HTML report: %s", err)
-				} else {
-					logger.Success("HTML report saved to %s", htmlOutput)
+// prepareOutputs validates the reporting options before any work starts.
+func (r *Runner) prepareOutputs() error {
+	outputs, err := report.ParseOutputs(r.options.Outputs, r.outputDefaults())
+	if err != nil {
+		return err
+	}
+	r.outputs = nil
+	for _, out := range outputs {
+		// HTML is rendered by xsltproc; without it the other outputs are
+		// still written.
+		if out.Format.Name == "html" {
+			if _, err := exec.LookPath("xsltproc"); err != nil {
+				logger.Warn("xsltproc is not installed, skipping HTML report %s", out.Path)
+				continue
+			}
+		}
+		r.outputs = append(r.outputs, out)
+	}
 
-					logger.Info("Keeping raw XML file for debugging: %s", xmlOutput)
+	summaryViews, err := report.ParseSummaryViews(r.options.Summary)
+	if err != nil {
+		return err
+	}
+	r.summaryViews = summaryViews
 
-					_ = os.Remove("nmap.xsl")
-				}
-			}
+	if r.options.Filter != "" {
+		if r.filter, err = core.ParseFilter(r.options.Filter); err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
 		}
-	} else {
-		logger.Info("No scan results to merge")
 	}
+	return nil
 }
 
-func (r *Runner) scanTarget(host string, ports []string, outputDir string) {
-	var validPorts []string
-	for _, p := range ports {
-		if p != "" {
-			validPorts = append(validPorts, p)
-		}
+// outputDefaults are the format options set by -columns and -md-template.
+func (r *Runner) outputDefaults() map[string]string {
+	return map[string]string{
+		"columns":  r.options.Columns,
+		"template": r.options.MarkdownTemplate,
 	}
-	portFlag := strings.Join(validPorts, ",")
+}
 
-	if !r.options.Silent {
-		if len(validPorts) > 0 {
-			logger.Info("Scanning %s with ports: %s", host, portFlag)
-		} else {
-			logger.Info("Scanning %s", host)
+func (r *Runner) scanTarget(parent context.Context, worker int, j *job, outputDir string, timeout time.Duration) (status scanStatus) {
+	host := j.Host
+	log := j.log
+
+	var result *nmap.NmapRun
+	record := core.JobRecord{ID: j.ID, Host: host, Ports: j.Ports, ExitCode: -1, Started: time.Now()}
+	jobsRunning.Add(1)
+	defer func() {
+		record.Status = status.String()
+		record.Duration = time.Since(record.Started).Seconds()
+		jobsRunning.Add(-1)
+		observeJob(record, result)
+		r.addRecord(record)
+		if r.OnJob != nil {
+			r.OnJob(record, result)
 		}
+	}()
+
+	var stats io.WriteCloser
+	if r.progress != nil {
+		stats = r.progress.JobStarted(worker, host)
+		defer func() {
+			stats.Close()
+			r.progress.JobFinished(worker, status == scanTimeout || status == scanFailed)
+		}()
 	}
 
-	safeHostName := strings.ReplaceAll(host, ".", "_")
-	safeHostName = strings.ReplaceAll(safeHostName, ":", "_") // Handle ipv6 if needed
+	concurrency := ""
+	if r.limiter.adaptive() {
+		concurrency = fmt.Sprintf(" [concurrency %d]", r.limiter.current())
+	}
+	if len(j.Ports) > 0 {
+		log.Info("Scanning %s with ports: %s%s", host, strings.Join(j.Ports, ","), concurrency)
+	} else {
+		log.Info("Scanning %s%s", host, concurrency)
+	}
+
+	safeHostName := safeName(host)
 	outputFile := filepath.Join(outputDir, fmt.Sprintf("%s.xml", safeHostName))
+	if r.sandboxExe != "" {
+		// A sandboxed nmap only writes to a directory of its own; the
+		// result is moved next to the others for merging.
+		workDir := filepath.Join(outputDir, fmt.Sprintf("job-%d", j.ID))
+		if err := os.MkdirAll(workDir, 0700); err != nil {
+			log.Error("Failed to create job directory: %s", err)
+			record.Error = err.Error()
+			return scanFailed
+		}
+		finalFile := outputFile
+		outputFile = filepath.Join(workDir, filepath.Base(outputFile))
+		defer func() {
+			os.Rename(outputFile, finalFile)
+			os.RemoveAll(workDir)
+		}()
+	}
 
-	flagsStr := r.options.NmapFlags
+	argv, err := r.jobCommand(j, outputFile, stats != nil)
+	if err != nil {
+		log.Error("Failed to parse nmap flags: %s", err)
+		record.Error = err.Error()
+		return scanFailed
+	}
+	record.Command = argv
+	record.Downgraded = r.downgraded
 
-	if r.options.DeepMode {
-		if os.Geteuid() != 0 {
-			logger.Warn("Deep Mode uses SYN scan (-sS) which requires root privileges. Scan may fail or degrade.")
-		}
-		flagsStr = "-sS -sV -sC --script vulners --reason --version-all -T4 -Pn -n --host-timeout 5m"
-		if !r.options.Silent {
-			logger.Info("Using Deep Scan Mode")
+	record.StdoutLog = filepath.Join(r.logDir, safeHostName+".stdout.log")
+	record.StderrLog = filepath.Join(r.logDir, safeHostName+".stderr.log")
+	stdoutFile, err := os.Create(record.StdoutLog)
+	if err != nil {
+		log.Error("Failed to create log file: %s", err)
+		record.Error = err.Error()
+		return scanFailed
+	}
+	defer stdoutFile.Close()
+	stderrFile, err := os.Create(record.StderrLog)
+	if err != nil {
+		log.Error("Failed to create log file: %s", err)
+		record.Error = err.Error()
+		return scanFailed
+	}
+	defer stderrFile.Close()
+
+	record.Timeout = timeout.Seconds()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := r.nmapCommand(ctx, argv)
+	cmd.Stdout = stdoutFile
+	cmd.Stderr = stderrFile
+	if stats != nil {
+		cmd.Stdout = io.MultiWriter(stdoutFile, stats)
+	}
+
+	err = cmd.Run()
+	record.ExitCode = cmd.ProcessState.ExitCode()
+	if err != nil {
+		record.Error = err.Error()
+		record.StderrTail, _ = tailLines(record.StderrLog, stderrTailLines)
+		if parent.Err() != nil {
+			log.Warn("Scan of %s cancelled", host)
+			return scanFailed
 		}
-	} else if r.options.FastMode {
-		if os.Geteuid() != 0 {
-			logger.Warn("Fast Mode uses SYN scan (-sS) which requires root privileges. Scan may fail or degrade.")
+		if ctx.Err() == context.DeadlineExceeded {
+			log.Error("Timeout scanning %s after %s", host, timeout)
+			return scanTimeout
 		}
-		flagsStr = "-sS -sV -T4 --top-ports 1000 -n -Pn --open --host-timeout 5m"
-		if !r.options.Silent {
-			logger.Info("Using Fast Scan Mode")
+		if len(record.StderrTail) > 0 {
+			log.Error("Error scanning %s: %s: %s", host, err, strings.Join(record.StderrTail, " | "))
+		} else {
+			log.Error("Error scanning %s: %s", host, err)
 		}
-	} else if flagsStr == "" {
-		flagsStr = "-sV -sS -T3 -Pn -n --host-timeout 5m"
+		return scanFailed
 	}
 
-	if !r.options.Silent {
-		logger.Info("Target %s flags: %s", host, flagsStr)
+	result, err = core.ParseXML(outputFile)
+	if err != nil {
+		log.Error("Failed to parse scan result for %s: %s", host, err)
+		record.Error = err.Error()
+		return scanFailed
+	}
+	if result.RunStats.Hosts.Up == 0 {
+		return scanHostDown
 	}
+	if r.scriptMap != nil {
+		r.scriptPhase(ctx, j, result, outputFile, &record, stdoutFile, stderrFile)
+	}
+	return scanOK
+}
 
-	args, err := shlex.Split(flagsStr)
+// nmapFlags returns the nmap flags for the selected scan mode.
+func (r *Runner) nmapFlags() string {
+	switch {
+	case r.flags != "":
+		return r.flags
+	case r.options.DeepMode && r.options.AutoScripts:
+		// The script phase replaces -sC with scripts picked per service.
+		return "-sS -sV --script vulners --reason --version-all -T4 -Pn -n --host-timeout 5m"
+	case r.options.DeepMode:
+		return "-sS -sV -sC --script vulners --reason --version-all -T4 -Pn -n --host-timeout 5m"
+	case r.options.FastMode:
+		return "-sS -sV -T4 --top-ports 1000 -n -Pn --open --host-timeout 5m"
+	case r.options.NmapFlags != "":
+		return r.options.NmapFlags
+	default:
+		return "-sV -sS -T3 -Pn -n --host-timeout 5m"
+	}
+}
+
+// jobCommand returns the command line of the service scan of j, as run by
+// scanTarget and planned by -dry-run. stats adds --stats-every for the
+// progress display.
+func (r *Runner) jobCommand(j *job, outputFile string, stats bool) ([]string, error) {
+	args, err := r.buildArgs(j, outputFile)
 	if err != nil {
-		logger.Error("Failed to parse nmap flags: %s", err)
-		return
+		return nil, err
 	}
+	if stats {
+		args = append(args[:len(args)-1], "--stats-every", statsEvery(r.options.StatsEvery), j.Host)
+	}
+	return r.commandLine(outputFile, args), nil
+}
 
-	args = append(args, "-oX", outputFile, "--webxml")
+// showProgress reports whether local scans feed the progress display.
+func (r *Runner) showProgress() bool {
+	return !r.options.Silent && !r.options.NoProgress
+}
 
-	if len(validPorts) > 0 {
-		args = append(args, "-p", portFlag)
-	}
+// buildArgs returns the nmap arguments for j writing XML to outputFile. The
+// target host is always the last argument.
+func (r *Runner) buildArgs(j *job, outputFile string) ([]string, error) {
+	flagsStr := r.nmapFlags()
+	j.log.Debug("Target %s flags: %s", j.Host, flagsStr)
 
-	args = append(args, host)
+	args, err := shlex.Split(flagsStr)
+	if err != nil {
+		return nil, err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.options.Timeout)*time.Minute)
-	defer cancel()
+	args = append(args, "-oX", outputFile, "--webxml")
 
-	cmd := exec.CommandContext(ctx, "nmap", args...)
+	if len(j.Ports) > 0 {
+		args = append(args, "-p", strings.Join(j.Ports, ","))
+	}
 
+	return append(args, j.Host), nil
+}
 
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			logger.Error("Timeout scanning %s", host)
-		} else {
-			logger.Error("Error scanning %s: %s", host, err)
+// buildJobs turns grouped targets into jobs ordered by host.
+func buildJobs(targets map[string][]string) []*job {
+	hosts := make([]string, 0, len(targets))
+	for host := range targets {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	jobs := make([]*job, 0, len(hosts))
+	for i, host := range hosts {
+		var ports []string
+		for _, p := range targets[host] {
+			if p != "" {
+				ports = append(ports, p)
+			}
 		}
-	} else {
+		jobs = append(jobs, &job{
+			ID:    i + 1,
+			Host:  host,
+			Ports: ports,
+			log:   logger.With("host", host, "job", i+1),
+		})
 	}
+	return jobs
 }
 
 func readLines(path string) ([]string, error) {
@@ -258,3 +616,9 @@ func hasStdin() bool {
 	}
 	return stat.Mode()&os.ModeCharDevice == 0
 }
+
+// statsEvery formats d for nmap's --stats-every, which takes a number with
+// a single unit suffix rather than Go's "1m30s".
+func statsEvery(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
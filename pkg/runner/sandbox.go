@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+)
+
+// SandboxCommand is the hidden chainmap subcommand that -sandbox starts
+// nmap through: "chainmap sandbox-exec <dir> -- nmap <args>".
+const SandboxCommand = "sandbox-exec"
+
+// prepareSandbox checks that -sandbox can be enforced before any job runs.
+func (r *Runner) prepareSandbox() error {
+	if !r.options.Sandbox {
+		return nil
+	}
+	exe, err := sandboxExecutable()
+	if err != nil {
+		return err
+	}
+	r.sandboxExe = exe
+	return nil
+}
+
+// sandboxExecutable returns the chainmap binary that wraps nmap in the
+// sandbox, failing when the kernel cannot enforce it.
+func sandboxExecutable() (string, error) {
+	abi, err := privileges.SandboxVersion()
+	if err != nil {
+		return "", fmt.Errorf("-sandbox: %w", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("-sandbox: %w", err)
+	}
+	logger.Info("Running nmap in a Landlock sandbox (ABI %d), writing only to its output directory", abi)
+	return exe, nil
+}
+
+// commandLine returns the program and arguments running nmap with args,
+// which write their output to outputFile. With -sandbox nmap is started by
+// chainmap and may only write to the directory of outputFile.
+func (r *Runner) commandLine(outputFile string, args []string) []string {
+	if r.sandboxExe == "" {
+		return append([]string{"nmap"}, args...)
+	}
+	return append([]string{r.sandboxExe, SandboxCommand, filepath.Dir(outputFile), "--", "nmap"}, args...)
+}
+
+// nmapCommand returns the command running argv from commandLine with the
+// privileges nmap needs.
+func (r *Runner) nmapCommand(ctx context.Context, argv []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	r.caps.Inherit(cmd)
+	return cmd
+}
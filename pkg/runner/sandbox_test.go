@@ -0,0 +1,26 @@
+package runner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func TestNmapCommand(t *testing.T) {
+	r := New(&options.Options{})
+	args := []string{"-sV", "-oX", "/tmp/scans/host.xml", "host"}
+
+	cmd := r.nmapCommand(context.Background(), r.commandLine("/tmp/scans/host.xml", args))
+	if want := append([]string{"nmap"}, args...); !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("nmapCommand() = %q, want %q", cmd.Args, want)
+	}
+
+	r.sandboxExe = "/usr/bin/chainmap"
+	cmd = r.nmapCommand(context.Background(), r.commandLine("/tmp/scans/host.xml", args))
+	want := append([]string{"/usr/bin/chainmap", SandboxCommand, "/tmp/scans", "--", "nmap"}, args...)
+	if cmd.Path != r.sandboxExe || !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("nmapCommand() with -sandbox = %s %q, want %q", cmd.Path, cmd.Args, want)
+	}
+}
@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/lair-framework/go-nmap"
+)
+
+// scriptFlags are used for the targeted script phase. Light version
+// detection keeps service-based script rules working on unusual ports.
+const scriptFlags = "-Pn -n -sV --version-light --host-timeout 5m"
+
+// scriptPhase runs the NSE scripts mapped to the services found in run as a
+// second nmap job against the same host and folds their output into
+// outputFile. It shares ctx, and so the timeout, with the service scan.
+// Failures are logged and leave the service scan results as is.
+func (r *Runner) scriptPhase(ctx context.Context, j *job, run *nmap.NmapRun, outputFile string, record *core.JobRecord, stdout, stderr io.Writer) {
+	var ports, scripts []string
+	for _, host := range run.Hosts {
+		if ports, scripts = r.scriptMap.SelectScripts(host); len(ports) > 0 {
+			break
+		}
+	}
+	if len(ports) == 0 {
+		j.log.Debug("No scripts mapped to the services on %s", j.Host)
+		return
+	}
+
+	// Worker script maps come from the coordinator, so the policy is
+	// checked here and not only when -script-map is loaded.
+	if err := r.policy.CheckScripts(scripts); err != nil {
+		j.log.Warn("Skipping script scan of %s: %s", j.Host, err)
+		return
+	}
+
+	scriptFile := scriptOutput(outputFile)
+	defer os.Remove(scriptFile)
+
+	argv, fit := r.scriptCommand(j, ports, scripts, scriptFile)
+	fit.logTo(j.log)
+	record.Downgraded = core.FlagChanges([]core.JobRecord{*record, {Downgraded: fit.changes}})
+	if argv == nil {
+		j.log.Warn("Skipping script scan of %s: UDP ports %s need raw socket access", j.Host, strings.Join(ports, ","))
+		return
+	}
+	record.ScriptCommand = argv
+	j.log.Info("Running %d scripts against %s ports %s", len(scripts), j.Host, strings.Join(ports, ","))
+
+	cmd := r.nmapCommand(ctx, argv)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			j.log.Warn("Script scan of %s ran out of job time, keeping service scan results", j.Host)
+			return
+		}
+		j.log.Warn("Script scan of %s failed, keeping service scan results: %s", j.Host, err)
+		return
+	}
+
+	scriptRun, err := core.ParseXML(scriptFile)
+	if err != nil {
+		j.log.Warn("Failed to parse script scan of %s: %s", j.Host, err)
+		return
+	}
+	core.MergeScriptResults(run, scriptRun)
+	if err := core.WriteXML(run, outputFile); err != nil {
+		j.log.Warn("Failed to save script results for %s: %s", j.Host, err)
+	}
+}
+
+// scriptOutput names the XML file of the script phase of the job writing
+// outputFile. It has no .xml extension, so a leftover file never ends up in
+// the merge.
+func scriptOutput(outputFile string) string {
+	return strings.TrimSuffix(outputFile, ".xml") + ".scripts"
+}
+
+// scriptCommand returns the command line of the script phase of j. Its
+// arguments are fitted to nmap's privileges like those of the service scan,
+// and fit holds what changed. UDP ports are left out when -auto-downgrade
+// drops -sU; argv is nil when no port is left.
+func (r *Runner) scriptCommand(j *job, ports, scripts []string, scriptFile string) (argv []string, fit *flagFit) {
+	fit = &flagFit{}
+	args := scriptArgs(ports, scripts)
+	// A coordinator's workers fit the script phase to their own nmap.
+	if r.options.Coordinator == "" {
+		args, fit.changes, fit.unsupported = fitPrivileges(args, r.caps, r.options.AutoDowngrade)
+	}
+	if !containsArg(args, "-sU") {
+		var tcp []string
+		for _, p := range ports {
+			if !strings.HasPrefix(p, "U:") {
+				tcp = append(tcp, p)
+			}
+		}
+		if len(tcp) == 0 {
+			return nil, fit
+		}
+		for i := len(args) - 2; i >= 0; i-- {
+			if args[i] == "-p" {
+				args[i+1] = strings.Join(tcp, ",")
+				break
+			}
+		}
+	}
+	args = append(args, "-oX", scriptFile, "--webxml", j.Host)
+	return r.commandLine(scriptFile, args), fit
+}
+
+func containsArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptArgs returns the nmap arguments, without output and target, of a
+// script scan of ports in "T:22,U:161" form. UDP ports need -sU, and -sS
+// next to it so that the TCP ports are scanned as well.
+func scriptArgs(ports, scripts []string) []string {
+	args := strings.Fields(scriptFlags)
+	var tcp, udp bool
+	for _, p := range ports {
+		tcp = tcp || strings.HasPrefix(p, "T:")
+		udp = udp || strings.HasPrefix(p, "U:")
+	}
+	if udp {
+		if tcp {
+			args = append(args, "-sS")
+		}
+		args = append(args, "-sU")
+	}
+	return append(args, "--script", strings.Join(scripts, ","), "-p", strings.Join(ports, ","))
+}
@@ -0,0 +1,103 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+)
+
+func TestScriptArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []string
+		want  string
+	}{
+		{"TCP", []string{"T:22", "T:80"}, scriptFlags + " --script a,b -p T:22,T:80"},
+		{"UDP", []string{"U:161"}, scriptFlags + " -sU --script a,b -p U:161"},
+		{"Both", []string{"T:22", "U:161"}, scriptFlags + " -sS -sU --script a,b -p T:22,U:161"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(scriptArgs(tt.ports, []string{"a", "b"}), " "); got != tt.want {
+				t.Errorf("scriptArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		caps        privileges.Capabilities
+		downgrade   bool
+		ports       []string
+		want        string
+		changes     int
+		unsupported []string
+	}{
+		{"Root", privileges.Capabilities{Root: true}, false, []string{"T:22", "U:161"},
+			scriptFlags + " -sS -sU --script a -p T:22,U:161", 0, nil},
+		{"Capabilities", privileges.Capabilities{NetRaw: true, NetAdmin: true}, false, []string{"U:161"},
+			scriptFlags + " -sU --script a -p U:161 --privileged", 0, nil},
+		{"Unprivileged", privileges.Capabilities{}, false, []string{"U:161"},
+			scriptFlags + " -sU --script a -p U:161", 0, []string{"-sU"}},
+		{"Downgrade", privileges.Capabilities{}, true, []string{"T:22", "U:161"},
+			scriptFlags + " -sT --script a -p T:22", 2, nil},
+		{"Downgrade UDP only", privileges.Capabilities{}, true, []string{"U:161"}, "", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(&options.Options{AutoDowngrade: tt.downgrade})
+			r.caps = tt.caps
+			j := &job{Host: "10.0.0.1"}
+			argv, fit := r.scriptCommand(j, tt.ports, []string{"a"}, "out.scripts")
+			want := []string(nil)
+			if tt.want != "" {
+				want = append(append([]string{"nmap"}, strings.Fields(tt.want)...), "-oX", "out.scripts", "--webxml", "10.0.0.1")
+			}
+			if strings.Join(argv, " ") != strings.Join(want, " ") {
+				t.Errorf("scriptCommand() = %q, want %q", argv, want)
+			}
+			if len(fit.changes) != tt.changes {
+				t.Errorf("changes = %v, want %d", fit.changes, tt.changes)
+			}
+			if strings.Join(fit.unsupported, " ") != strings.Join(tt.unsupported, " ") {
+				t.Errorf("unsupported = %v, want %v", fit.unsupported, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestLoadScriptMap(t *testing.T) {
+	dir := t.TempDir()
+	mapFile := filepath.Join(dir, "scripts.yaml")
+	os.WriteFile(mapFile, []byte("http: [http-title, /tmp/x.nse]\n"), 0644)
+
+	tests := []struct {
+		name    string
+		opts    options.Options
+		policy  *core.FlagPolicy
+		wantErr bool
+	}{
+		{"Disabled", options.Options{ScriptMap: mapFile}, &core.FlagPolicy{}, false},
+		{"Default map", options.Options{AutoScripts: true}, &core.FlagPolicy{}, false},
+		{"Path without policy", options.Options{AutoScripts: true, ScriptMap: mapFile}, nil, false},
+		{"Path refused", options.Options{AutoScripts: true, ScriptMap: mapFile}, &core.FlagPolicy{}, true},
+		{"Path allowed", options.Options{AutoScripts: true, ScriptMap: mapFile}, &core.FlagPolicy{ScriptPaths: true}, false},
+		{"Scripts denied", options.Options{AutoScripts: true}, &core.FlagPolicy{Deny: []string{"--script"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(&tt.opts)
+			r.policy = tt.policy
+			if err := r.loadScriptMap(); (err != nil) != tt.wantErr {
+				t.Errorf("loadScriptMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
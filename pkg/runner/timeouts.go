@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+)
+
+// defaultPortCount is what nmap scans without -p: its top 1000 ports.
+// -timeout is meant for this many ports when -scale-timeout is on.
+const defaultPortCount = 1000
+
+// minScaledTimeout keeps -scale-timeout from starving hosts with few ports.
+const minScaledTimeout = time.Minute
+
+// planJobs turns target lines into jobs, applying the per-target timeouts
+// from "timeout=" tokens and the -timeouts file. Inline values win.
+func (r *Runner) planJobs(lines []string) ([]*job, error) {
+	lines, inline, err := core.SplitTimeouts(lines)
+	if err != nil {
+		return nil, err
+	}
+	jobList := buildJobs(core.ParseTargets(lines))
+	for _, j := range jobList {
+		if d, ok := inline[j.Host]; ok {
+			j.Timeout = d
+		} else if d, ok := r.timeouts[j.Host]; ok {
+			j.Timeout = d
+		}
+	}
+	return jobList, nil
+}
+
+// loadTimeouts reads the -timeouts sidecar file.
+func (r *Runner) loadTimeouts() error {
+	if r.options.TimeoutFile == "" {
+		return nil
+	}
+	timeouts, err := core.LoadTimeouts(r.options.TimeoutFile)
+	if err != nil {
+		return fmt.Errorf("could not load timeouts: %w", err)
+	}
+	r.timeouts = timeouts
+	return nil
+}
+
+// jobTimeout returns how long nmap may run for j: its own timeout if it has
+// one, otherwise -timeout, scaled by port count with -scale-timeout. The
+// result never runs past the -budget deadline; ok is false when no time is
+// left and j should be skipped.
+func (r *Runner) jobTimeout(j *job) (timeout time.Duration, ok bool) {
+	timeout = j.Timeout
+	if timeout == 0 {
+		timeout = time.Duration(r.options.Timeout) * time.Minute
+		if r.options.ScaleTimeout {
+			timeout = timeout * time.Duration(r.portCount(j)) / defaultPortCount
+			if timeout < minScaledTimeout {
+				timeout = minScaledTimeout
+			}
+		}
+	}
+	if !r.deadline.IsZero() {
+		remaining := time.Until(r.deadline)
+		if remaining <= 0 {
+			return 0, false
+		}
+		if remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout, true
+}
+
+// portCount estimates how many ports nmap will scan for j from its ports or
+// the -p, --top-ports and -F flags.
+func (r *Runner) portCount(j *job) int {
+	if len(j.Ports) > 0 {
+		return core.CountPorts(j.Ports)
+	}
+	args, err := shlex.Split(r.nmapFlags())
+	if err != nil {
+		return defaultPortCount
+	}
+	count := defaultPortCount
+	for i, arg := range args {
+		next := ""
+		if i+1 < len(args) {
+			next = args[i+1]
+		}
+		switch {
+		case arg == "-p":
+			count = core.CountPorts([]string{next})
+		case strings.HasPrefix(arg, "-p") && !strings.HasPrefix(arg, "-P"):
+			count = core.CountPorts([]string{arg[2:]})
+		case arg == "--top-ports":
+			if n, err := strconv.Atoi(next); err == nil {
+				count = n
+			}
+		case arg == "-F":
+			count = 100
+		}
+	}
+	return count
+}
+
+// budgetExhausted reports whether the -budget deadline has passed.
+func (r *Runner) budgetExhausted() bool {
+	return !r.deadline.IsZero() && !time.Now().Before(r.deadline)
+}
+
+// skipJob records j as not scanned because the budget ran out.
+func (r *Runner) skipJob(j *job) {
+	r.budgetOnce.Do(func() {
+		logger.Warn("Scan budget of %s exhausted, skipping the remaining jobs", r.options.Budget)
+	})
+	j.log.Debug("Skipping %s, scan budget exhausted", j.Host)
+
+	record := core.JobRecord{
+		ID:       j.ID,
+		Host:     j.Host,
+		Ports:    j.Ports,
+		Status:   scanSkipped.String(),
+		ExitCode: -1,
+		Started:  time.Now(),
+		Error:    "scan budget exhausted",
+	}
+	r.addRecord(record)
+	observeJob(record, nil)
+	if r.progress != nil {
+		r.progress.JobSkipped()
+	}
+	if r.OnJob != nil {
+		r.OnJob(record, nil)
+	}
+}
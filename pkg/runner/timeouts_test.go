@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/internal/nmaptest"
+	"github.com/ihsanlearn/chainmap/options"
+)
+
+func TestJobTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		opts options.Options
+		job  job
+		want time.Duration
+	}{
+		{"Global", options.Options{Timeout: 10}, job{}, 10 * time.Minute},
+		{"Override", options.Options{Timeout: 10, ScaleTimeout: true}, job{Ports: []string{"22"}, Timeout: time.Hour}, time.Hour},
+		{"Scaled default ports", options.Options{Timeout: 10, ScaleTimeout: true}, job{}, 10 * time.Minute},
+		{"Scaled two ports", options.Options{Timeout: 10, ScaleTimeout: true}, job{Ports: []string{"22", "80"}}, time.Minute},
+		{"Scaled all ports", options.Options{Timeout: 10, ScaleTimeout: true}, job{Ports: []string{"1-65535"}}, 65535 * 10 * time.Minute / 1000},
+		{"Scaled -p-", options.Options{Timeout: 10, ScaleTimeout: true, NmapFlags: "-sS -p- -T4"}, job{}, 65535 * 10 * time.Minute / 1000},
+		{"Scaled top ports", options.Options{Timeout: 10, ScaleTimeout: true, NmapFlags: "-sS --top-ports 5000"}, job{}, 50 * time.Minute},
+		{"Scaled fast mode", options.Options{Timeout: 10, ScaleTimeout: true, FastMode: true}, job{}, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(&tt.opts)
+			if got, ok := r.jobTimeout(&tt.job); got != tt.want || !ok {
+				t.Errorf("jobTimeout() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	r := New(&options.Options{Timeout: 10})
+	r.deadline = time.Now().Add(time.Minute)
+	if got, ok := r.jobTimeout(&job{}); got > time.Minute || !ok {
+		t.Errorf("jobTimeout() with budget = %s, %v, want at most 1m", got, ok)
+	}
+	r.deadline = time.Now().Add(-time.Second)
+	if got, ok := r.jobTimeout(&job{}); ok {
+		t.Errorf("jobTimeout() past the budget = %s, want the job skipped", got)
+	}
+}
+
+func TestPlanJobsTimeouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeouts.txt")
+	os.WriteFile(path, []byte("10.0.0.1 1h\n10.0.0.2 2h\n"), 0644)
+
+	r := New(&options.Options{TimeoutFile: path})
+	if err := r.loadTimeouts(); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := r.planJobs([]string{"10.0.0.1", "10.0.0.2:22 timeout=5m", "10.0.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"10.0.0.1": time.Hour, "10.0.0.2": 5 * time.Minute, "10.0.0.3": 0}
+	for _, j := range jobs {
+		if j.Timeout != want[j.Host] {
+			t.Errorf("%s timeout = %s, want %s", j.Host, j.Timeout, want[j.Host])
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	nmaptest.Install(t)
+	t.Setenv("FAKE_SLEEP", "0.3")
+	dir := t.TempDir()
+	opts := &options.Options{
+		Targets:    []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		Threads:    1,
+		MinThreads: 1,
+		Timeout:    10,
+		Budget:     500 * time.Millisecond,
+		Outputs:    []string{filepath.Join(dir, "results.xml")},
+		StateDir:   filepath.Join(dir, "state"),
+		Summary:    "none",
+		NoProgress: true,
+	}
+
+	start := time.Now()
+	if err := New(opts).Run(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("run took %s with a 500ms budget", elapsed)
+	}
+
+	m, err := core.ReadManifest(filepath.Join(opts.StateDir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]int{}
+	for _, rec := range m.Jobs {
+		statuses[rec.Status]++
+	}
+	if len(m.Jobs) != 4 || statuses["ok"] != 1 || statuses["not_scanned"] < 2 {
+		t.Errorf("job statuses = %v", statuses)
+	}
+}
@@ -0,0 +1,248 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/privileges"
+	"github.com/lair-framework/go-nmap"
+)
+
+// pollInterval is how long an idle worker waits before asking for work again.
+var pollInterval = 2 * time.Second
+
+// Worker runs jobs leased from a coordinator.
+type Worker struct {
+	Coordinator string
+	Token       string
+	Name        string
+	Threads     int
+
+	options *options.Options
+	client  *http.Client
+	caps    privileges.Capabilities
+	policy  *core.FlagPolicy
+	// sandboxExe wraps nmap with -sandbox, see Runner.
+	sandboxExe string
+	// fitOnce reports how leased flags were fitted to caps once, since
+	// every lease carries the same flags.
+	fitOnce sync.Once
+}
+
+// NewWorker returns a worker that scans with opts, which supplies the state
+// directory and timeouts. Flags and ports come from each lease.
+func NewWorker(opts *options.Options, coordinator, token, name string) *Worker {
+	return &Worker{
+		Coordinator: strings.TrimRight(coordinator, "/"),
+		Token:       token,
+		Name:        name,
+		Threads:     opts.Threads,
+		options:     opts,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run leases and scans jobs with Threads parallel slots until ctx is
+// cancelled.
+func (w *Worker) Run(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Join(w.options.StateDir, "logs"), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp("", "chainmap-worker")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if w.options.MetricsAddr != "" {
+		stop, err := serveMetrics(w.options.MetricsAddr)
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	w.caps = detectPrivileges()
+	if w.options.Sandbox {
+		if w.sandboxExe, err = sandboxExecutable(); err != nil {
+			return err
+		}
+		w.caps = w.caps.Sandboxed()
+	}
+	if w.options.FlagPolicy != "" {
+		if w.policy, err = core.LoadFlagPolicy(w.options.FlagPolicy); err != nil {
+			return fmt.Errorf("could not load flag policy: %w", err)
+		}
+	}
+
+	logger.Info("Worker %s polling %s with %d slots", w.Name, w.Coordinator, w.Threads)
+	concurrency.Add(float64(w.Threads))
+	defer concurrency.Add(-float64(w.Threads))
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.Threads; i++ {
+		wg.Add(1)
+		go func(slot int) {
+			defer wg.Done()
+			w.loop(ctx, slot, filepath.Join(tempDir, fmt.Sprint(slot)))
+		}(i)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (w *Worker) loop(ctx context.Context, slot int, dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logger.Error("Worker slot %d: %s", slot, err)
+		return
+	}
+	for ctx.Err() == nil {
+		lease, err := w.lease(ctx)
+		if err != nil {
+			logger.Debug("Lease request failed: %s", err)
+		}
+		if lease == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		w.scan(ctx, slot, dir, lease)
+	}
+}
+
+// scan runs one leased job, sending heartbeats while nmap runs and
+// abandoning the job if the coordinator reassigned it.
+func (w *Worker) scan(ctx context.Context, slot int, dir string, lease *Lease) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	heartbeats := make(chan struct{})
+	defer func() {
+		cancel()
+		<-heartbeats
+	}()
+
+	go func() {
+		defer close(heartbeats)
+		ticker := time.NewTicker(leaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if err := w.post(jobCtx, fmt.Sprintf("/cluster/jobs/%d/heartbeat", lease.JobID), JobResult{Token: lease.Token}, nil); err == errLeaseLost {
+					logger.Warn("Coordinator reassigned %s, stopping", lease.Host)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	opts := *w.options
+	opts.NmapFlags = lease.NmapFlags
+	opts.FastMode, opts.DeepMode = false, false
+	r := New(&opts)
+	r.limiter = newConcurrencyLimiter(1, 1)
+	r.logDir = filepath.Join(opts.StateDir, "logs")
+	r.scriptMap = lease.Scripts
+	r.policy = w.policy
+	r.sandboxExe = w.sandboxExe
+	r.caps = w.caps
+
+	var record core.JobRecord
+	r.OnJob = func(rec core.JobRecord, _ *nmap.NmapRun) { record = rec }
+
+	j := &job{
+		ID:      lease.JobID,
+		Host:    lease.Host,
+		Ports:   lease.Ports,
+		Timeout: lease.Timeout,
+		log:     logger.With("host", lease.Host, "job", lease.JobID, "worker", w.Name),
+	}
+	if fit, err := r.prepareFlags(&w.caps); err != nil {
+		// The coordinator's flags break this worker's policy; the job
+		// fails rather than going to another worker that may accept it.
+		j.log.Error("Refusing %s: %s", lease.Host, err)
+		record = core.JobRecord{ID: j.ID, Host: j.Host, Ports: j.Ports, Status: scanFailed.String(), ExitCode: -1, Started: time.Now(), Error: err.Error()}
+	} else {
+		w.fitOnce.Do(fit.report)
+		// Workers have no -budget; the coordinator fits the lease timeout
+		// to its own.
+		timeout, _ := r.jobTimeout(j)
+		r.scanTarget(jobCtx, slot, j, dir, timeout)
+		if jobCtx.Err() != nil {
+			return
+		}
+	}
+
+	result := JobResult{Token: lease.Token, Record: record}
+	xmlPath := filepath.Join(dir, safeName(lease.Host)+".xml")
+	if data, err := os.ReadFile(xmlPath); err == nil {
+		result.XML = string(data)
+		os.Remove(xmlPath)
+	}
+	if err := w.post(ctx, fmt.Sprintf("/cluster/jobs/%d/result", lease.JobID), result, nil); err != nil {
+		logger.Warn("Failed to report %s to the coordinator: %s", lease.Host, err)
+	}
+}
+
+// lease asks the coordinator for a job. It returns nil when none is ready.
+func (w *Worker) lease(ctx context.Context) (*Lease, error) {
+	var lease Lease
+	if err := w.post(ctx, "/cluster/lease", LeaseRequest{Worker: w.Name}, &lease); err != nil {
+		return nil, err
+	}
+	if lease.Token == "" {
+		return nil, nil
+	}
+	return &lease, nil
+}
+
+func (w *Worker) post(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Coordinator+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.Token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusConflict:
+		return errLeaseLost
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode >= 300:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("coordinator returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/pkg/report"
+)
+
+// Status is the lifecycle state of a scan.
+type Status string
+
+const (
+	StatusQueued      Status = "queued"
+	StatusRunning     Status = "running"
+	StatusCompleted   Status = "completed"
+	StatusFailed      Status = "failed"
+	StatusCancelled   Status = "cancelled"
+	StatusInterrupted Status = "interrupted"
+)
+
+func (s Status) finished() bool {
+	return s != StatusQueued && s != StatusRunning
+}
+
+// ScanRequest is the body of POST /api/scans.
+type ScanRequest struct {
+	Targets     []string `json:"targets"`
+	Profile     string   `json:"profile,omitempty"`
+	NmapFlags   string   `json:"nmap_flags,omitempty"`
+	AutoScripts bool     `json:"auto_scripts,omitempty"`
+	Threads     int      `json:"threads,omitempty"`
+	Timeout     int      `json:"timeout,omitempty"`
+}
+
+// HostResult is a finished job with the open ports it found.
+type HostResult struct {
+	Job   core.JobRecord    `json:"job"`
+	Ports []report.OpenPort `json:"ports"`
+}
+
+// Scan is one API scan. Its exported fields are persisted as scan.json in
+// the scan directory.
+type Scan struct {
+	ID       string       `json:"id"`
+	Request  ScanRequest  `json:"request"`
+	Status   Status       `json:"status"`
+	Error    string       `json:"error,omitempty"`
+	Created  time.Time    `json:"created"`
+	Started  *time.Time   `json:"started,omitempty"`
+	Finished *time.Time   `json:"finished,omitempty"`
+	Total    int          `json:"total"`
+	Done     int          `json:"done"`
+	Failed   int          `json:"failed"`
+	Hosts    []HostResult `json:"hosts"`
+
+	dir         string
+	mu          sync.Mutex
+	cancel      context.CancelFunc
+	subscribers map[chan Event]struct{}
+}
+
+// Event is a server-sent event about a scan.
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Snapshot returns a copy of the scan that is safe to encode.
+func (s *Scan) Snapshot() *Scan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+func (s *Scan) snapshotLocked() *Scan {
+	return &Scan{
+		ID:       s.ID,
+		Request:  s.Request,
+		Status:   s.Status,
+		Error:    s.Error,
+		Created:  s.Created,
+		Started:  s.Started,
+		Finished: s.Finished,
+		Total:    s.Total,
+		Done:     s.Done,
+		Failed:   s.Failed,
+		Hosts:    append([]HostResult(nil), s.Hosts...),
+	}
+}
+
+// Subscribe returns a channel that first replays the finished hosts and the
+// current status, then receives live events until the scan finishes or
+// unsubscribe is called.
+func (s *Scan) Subscribe() (events <-chan Event, unsubscribe func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Event, len(s.Hosts)+64)
+	for _, h := range s.Hosts {
+		ch <- Event{Type: "host", Data: h}
+	}
+	ch <- Event{Type: "status", Data: s.snapshotLocked()}
+	if s.Status.finished() {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if s.subscribers == nil {
+		s.subscribers = make(map[chan Event]struct{})
+	}
+	s.subscribers[ch] = struct{}{}
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publishLocked sends ev to every subscriber, dropping it for subscribers
+// that are too slow to keep up.
+func (s *Scan) publishLocked(ev Event) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+	if ev.Type == "status" && s.Status.finished() {
+		for ch := range s.subscribers {
+			close(ch)
+		}
+		s.subscribers = nil
+	}
+}
+
+// Store keeps scans in memory and persists each one under its own
+// directory, so finished scans and their results survive restarts.
+type Store struct {
+	dir   string
+	mu    sync.Mutex
+	scans map[string]*Scan
+}
+
+// OpenStore loads the scans saved in dir. Scans that were still queued or
+// running when the server stopped are marked interrupted.
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	st := &Store{dir: dir, scans: make(map[string]*Scan)}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name(), "scan.json"))
+		if err != nil {
+			continue
+		}
+		scan := &Scan{}
+		if err := json.Unmarshal(data, scan); err != nil {
+			continue
+		}
+		scan.dir = filepath.Join(dir, e.Name())
+		if !scan.Status.finished() {
+			scan.Status = StatusInterrupted
+			scan.Error = "server stopped before the scan finished"
+			st.save(scan)
+		}
+		st.scans[scan.ID] = scan
+	}
+	return st, nil
+}
+
+// Create adds a queued scan for req.
+func (st *Store) Create(req ScanRequest) (*Scan, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	scan := &Scan{
+		ID:      id,
+		Request: req,
+		Status:  StatusQueued,
+		Created: time.Now().UTC(),
+		Hosts:   []HostResult{},
+		dir:     filepath.Join(st.dir, id),
+	}
+	if err := os.MkdirAll(scan.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := st.save(scan); err != nil {
+		return nil, err
+	}
+
+	st.mu.Lock()
+	st.scans[id] = scan
+	st.mu.Unlock()
+	return scan, nil
+}
+
+// Get returns the scan with id, or nil.
+func (st *Store) Get(id string) *Scan {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.scans[id]
+}
+
+// List returns snapshots of all scans, newest first.
+func (st *Store) List() []*Scan {
+	st.mu.Lock()
+	list := make([]*Scan, 0, len(st.scans))
+	for _, s := range st.scans {
+		list = append(list, s)
+	}
+	st.mu.Unlock()
+
+	snaps := make([]*Scan, len(list))
+	for i, s := range list {
+		snaps[i] = s.Snapshot()
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Created.After(snaps[j].Created) })
+	return snaps
+}
+
+// Update applies fn to scan under its lock, persists it and notifies
+// subscribers with ev, or with the new status when ev is nil.
+func (st *Store) Update(scan *Scan, fn func(s *Scan), ev *Event) error {
+	scan.mu.Lock()
+	defer scan.mu.Unlock()
+
+	fn(scan)
+	if ev == nil {
+		ev = &Event{Type: "status", Data: scan.snapshotLocked()}
+	}
+	scan.publishLocked(*ev)
+	return st.save(scan)
+}
+
+func (st *Store) save(scan *Scan) error {
+	data, err := json.MarshalIndent(scan, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(scan.dir, "scan.json.tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(scan.dir, "scan.json"))
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
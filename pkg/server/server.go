@@ -0,0 +1,471 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+	"github.com/ihsanlearn/chainmap/core"
+	"github.com/ihsanlearn/chainmap/logger"
+	"github.com/ihsanlearn/chainmap/options"
+	"github.com/ihsanlearn/chainmap/pkg/metrics"
+	"github.com/ihsanlearn/chainmap/pkg/report"
+	"github.com/ihsanlearn/chainmap/pkg/runner"
+	"github.com/lair-framework/go-nmap"
+)
+
+// Profiles are the scan profiles accepted in a ScanRequest.
+var Profiles = []string{"default", "fast", "deep", "custom"}
+
+// Config configures the API server.
+type Config struct {
+	Addr             string
+	DataDir          string
+	Token            string
+	MaxScans         int
+	AllowCustomFlags bool
+	// FlagPolicy is a YAML flag policy file for custom nmap flags. Without
+	// one, custom flags may not load scripts or data files from disk.
+	FlagPolicy string
+	// Metrics exposes the runner metrics at GET /metrics.
+	Metrics bool
+	// MaxThreads and MaxTimeout cap the threads and the timeout in minutes
+	// a scan request may ask for.
+	MaxThreads int
+	MaxTimeout int
+}
+
+// Defaults of Config.MaxThreads and Config.MaxTimeout.
+const (
+	defaultMaxThreads = 50
+	defaultMaxTimeout = 720
+)
+
+// Server runs scans submitted over its REST API.
+type Server struct {
+	cfg    Config
+	store  *Store
+	slots  chan struct{}
+	policy *core.FlagPolicy
+}
+
+// New opens the job store in cfg.DataDir.
+func New(cfg Config) (*Server, error) {
+	if cfg.MaxScans < 1 {
+		cfg.MaxScans = 1
+	}
+	if cfg.MaxThreads < 1 {
+		cfg.MaxThreads = defaultMaxThreads
+	}
+	if cfg.MaxTimeout < 1 {
+		cfg.MaxTimeout = defaultMaxTimeout
+	}
+	policy := &core.FlagPolicy{}
+	if cfg.FlagPolicy != "" {
+		p, err := core.LoadFlagPolicy(cfg.FlagPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("could not load flag policy: %w", err)
+		}
+		policy = p
+	}
+	st, err := OpenStore(cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store: %w", err)
+	}
+	return &Server{cfg: cfg, store: st, slots: make(chan struct{}, cfg.MaxScans), policy: policy}, nil
+}
+
+// ListenAndServe serves the API until ctx is cancelled, then cancels the
+// running scans.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{Addr: s.cfg.Addr, Handler: s.Handler()}
+
+	go func() {
+		<-ctx.Done()
+		for _, scan := range s.store.List() {
+			s.cancelScan(s.store.Get(scan.ID))
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info("API listening on http://%s", s.cfg.Addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler returns the API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/scans", s.handleCreate)
+	mux.HandleFunc("GET /api/scans", s.handleList)
+	mux.HandleFunc("GET /api/scans/{id}", s.handleGet)
+	mux.HandleFunc("GET /api/scans/{id}/events", s.handleEvents)
+	mux.HandleFunc("GET /api/scans/{id}/results", s.handleResults)
+	mux.HandleFunc("POST /api/scans/{id}/cancel", s.handleCancel)
+	if s.cfg.Metrics {
+		mux.Handle("GET /metrics", metrics.Default.Handler())
+	}
+	return s.authenticate(mux)
+}
+
+// authenticate requires "Authorization: Bearer <token>" when a token is set.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	if s.cfg.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.cfg.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req ScanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if err := s.validate(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scan, err := s.store.Create(req)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	logger.Info("Scan %s queued with %d targets (%s profile)", scan.ID, len(req.Targets), req.Profile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scan.mu.Lock()
+	scan.cancel = cancel
+	scan.mu.Unlock()
+	go s.execute(ctx, scan)
+
+	w.Header().Set("Location", "/api/scans/"+scan.ID)
+	writeJSON(w, http.StatusCreated, scan.Snapshot())
+}
+
+func (s *Server) validate(req *ScanRequest) error {
+	var targets []string
+	for _, t := range req.Targets {
+		if t = strings.TrimSpace(t); t != "" {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return errors.New("targets must not be empty")
+	}
+	for _, t := range targets {
+		if strings.HasPrefix(t, "-") {
+			return fmt.Errorf("invalid target %q", t)
+		}
+	}
+	req.Targets = targets
+
+	if req.Profile == "" {
+		req.Profile = "default"
+		if req.NmapFlags != "" {
+			req.Profile = "custom"
+		}
+	}
+	valid := false
+	for _, p := range Profiles {
+		valid = valid || p == req.Profile
+	}
+	if !valid {
+		return fmt.Errorf("unknown profile %q (available: %s)", req.Profile, strings.Join(Profiles, ", "))
+	}
+	if req.Profile == "custom" {
+		if !s.cfg.AllowCustomFlags {
+			return errors.New("custom nmap flags are disabled on this server")
+		}
+		if req.NmapFlags == "" {
+			return errors.New("the custom profile needs nmap_flags")
+		}
+		args, err := shlex.Split(req.NmapFlags)
+		if err != nil {
+			return fmt.Errorf("invalid nmap_flags: %w", err)
+		}
+		if _, _, err := core.SanitizeFlags(args, s.policy); err != nil {
+			return fmt.Errorf("invalid nmap_flags: %w", err)
+		}
+	} else if req.NmapFlags != "" {
+		return errors.New("nmap_flags can only be used with the custom profile")
+	}
+	if req.Threads < 0 || req.Timeout < 0 {
+		return errors.New("threads and timeout must not be negative")
+	}
+	if req.Threads > s.cfg.MaxThreads {
+		return fmt.Errorf("threads must not exceed %d", s.cfg.MaxThreads)
+	}
+	if req.Timeout > s.cfg.MaxTimeout {
+		return fmt.Errorf("timeout must not exceed %d minutes", s.cfg.MaxTimeout)
+	}
+	return nil
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	scans := s.store.List()
+	// Host details can be large; they are available per scan.
+	for _, scan := range scans {
+		scan.Hosts = nil
+	}
+	writeJSON(w, http.StatusOK, scans)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	scan := s.store.Get(r.PathValue("id"))
+	if scan == nil {
+		writeError(w, http.StatusNotFound, "scan not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, scan.Snapshot())
+}
+
+// handleEvents streams "host" and "status" events as server-sent events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	scan := s.store.Get(r.PathValue("id"))
+	if scan == nil {
+		writeError(w, http.StatusNotFound, "scan not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	events, unsubscribe := scan.Subscribe()
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// handleResults serves the merged results of a finished scan, rendering
+// the requested format on first use.
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	scan := s.store.Get(r.PathValue("id"))
+	if scan == nil {
+		writeError(w, http.StatusNotFound, "scan not found")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "xml"
+	}
+	f := report.LookupFormat(format)
+	if f == nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown format %q", format))
+		return
+	}
+
+	snap := scan.Snapshot()
+	if !snap.Status.finished() {
+		writeError(w, http.StatusConflict, "scan is still "+string(snap.Status))
+		return
+	}
+	xmlPath := filepath.Join(scan.dir, "results.xml")
+	if _, err := os.Stat(xmlPath); err != nil {
+		writeError(w, http.StatusNotFound, "scan produced no results")
+		return
+	}
+
+	ext := f.Extensions[0]
+	path := filepath.Join(scan.dir, "results"+ext)
+	scan.mu.Lock()
+	_, statErr := os.Stat(path)
+	if statErr != nil {
+		statErr = runner.WriteReport(s.scanOptions(scan), xmlPath, path)
+	}
+	scan.mu.Unlock()
+	if statErr != nil {
+		writeError(w, http.StatusInternalServerError, "failed to render results: "+statErr.Error())
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=chainmap-%s%s", scan.ID, ext))
+	http.ServeFile(w, r, path)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	scan := s.store.Get(r.PathValue("id"))
+	if scan == nil {
+		writeError(w, http.StatusNotFound, "scan not found")
+		return
+	}
+	if scan.Snapshot().Status.finished() {
+		writeError(w, http.StatusConflict, "scan already finished")
+		return
+	}
+	s.cancelScan(scan)
+	logger.Info("Scan %s cancelled by client", scan.ID)
+	writeJSON(w, http.StatusAccepted, scan.Snapshot())
+}
+
+func (s *Server) cancelScan(scan *Scan) {
+	scan.mu.Lock()
+	cancel := scan.cancel
+	scan.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// execute waits for a free scan slot and runs scan.
+func (s *Server) execute(ctx context.Context, scan *Scan) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finish(scan, StatusCancelled, nil)
+		return
+	}
+	if ctx.Err() != nil {
+		s.finish(scan, StatusCancelled, nil)
+		return
+	}
+
+	s.store.Update(scan, func(sc *Scan) {
+		now := time.Now().UTC()
+		sc.Status = StatusRunning
+		sc.Started = &now
+	}, nil)
+	logger.Info("Scan %s started", scan.ID)
+
+	r := runner.New(s.scanOptions(scan))
+	r.OnStart = func(total int) {
+		s.store.Update(scan, func(sc *Scan) { sc.Total = total }, nil)
+	}
+	r.OnJob = func(record core.JobRecord, result *nmap.NmapRun) {
+		host := HostResult{Job: record, Ports: openPorts(result)}
+		s.store.Update(scan, func(sc *Scan) {
+			sc.Done++
+			if record.Failed() {
+				sc.Failed++
+			}
+			sc.Hosts = append(sc.Hosts, host)
+		}, &Event{Type: "host", Data: host})
+	}
+
+	err := r.RunContext(ctx)
+	switch {
+	case ctx.Err() != nil:
+		s.finish(scan, StatusCancelled, nil)
+	case err != nil:
+		s.finish(scan, StatusFailed, err)
+	default:
+		s.finish(scan, StatusCompleted, nil)
+	}
+}
+
+func (s *Server) finish(scan *Scan, status Status, err error) {
+	s.store.Update(scan, func(sc *Scan) {
+		now := time.Now().UTC()
+		sc.Status = status
+		sc.Finished = &now
+		if err != nil {
+			sc.Error = err.Error()
+		}
+	}, nil)
+	logger.Info("Scan %s %s", scan.ID, status)
+}
+
+// scanOptions builds runner options for scan, keeping all files in the
+// scan directory. Threads and timeout stay within the server's caps.
+func (s *Server) scanOptions(scan *Scan) *options.Options {
+	req := scan.Request
+	opts := &options.Options{
+		Targets:     req.Targets,
+		Threads:     min(5, s.cfg.MaxThreads),
+		MinThreads:  1,
+		Timeout:     min(10, s.cfg.MaxTimeout),
+		AutoScripts: req.AutoScripts,
+		Outputs:     []string{filepath.Join(scan.dir, "results.xml")},
+		StateDir:    filepath.Join(scan.dir, "state"),
+		Summary:     "none",
+		NoProgress:  true,
+		StatsEvery:  10 * time.Second,
+	}
+	if req.Threads > 0 {
+		opts.Threads = min(req.Threads, s.cfg.MaxThreads)
+	}
+	if req.Timeout > 0 {
+		opts.Timeout = min(req.Timeout, s.cfg.MaxTimeout)
+	}
+	switch req.Profile {
+	case "fast":
+		opts.FastMode = true
+	case "deep":
+		opts.DeepMode = true
+	case "custom":
+		opts.NmapFlags = req.NmapFlags
+	}
+	return opts
+}
+
+func openPorts(run *nmap.NmapRun) []report.OpenPort {
+	ports := []report.OpenPort{}
+	if run == nil {
+		return ports
+	}
+	summary := report.BuildSummary(run, nil, nil, []string{"ports"})
+	return append(ports, summary.OpenPorts...)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ihsanlearn/chainmap/internal/nmaptest"
+)
+
+func newTestServer(t *testing.T, cfg Config) *httptest.Server {
+	t.Helper()
+	cfg.DataDir = t.TempDir()
+	srv, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func request(t *testing.T, method, url, body string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer test")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func TestScanLifecycle(t *testing.T) {
+	nmaptest.Install(t)
+	ts := newTestServer(t, Config{Token: "test", Metrics: true})
+
+	resp, body := request(t, "POST", ts.URL+"/api/scans", `{"targets":["10.0.0.1","10.0.0.2"]}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", resp.StatusCode, body)
+	}
+	var scan Scan
+	if err := json.Unmarshal(body, &scan); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !scan.Status.finished() {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan did not finish, status %s", scan.Status)
+		}
+		time.Sleep(50 * time.Millisecond)
+		_, body = request(t, "GET", ts.URL+"/api/scans/"+scan.ID, "")
+		if err := json.Unmarshal(body, &scan); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if scan.Status != StatusCompleted || scan.Total != 2 || scan.Done != 2 || len(scan.Hosts) != 2 {
+		t.Fatalf("unexpected scan state: %s", body)
+	}
+	if len(scan.Hosts[0].Ports) != 1 || scan.Hosts[0].Ports[0].Port != 22 {
+		t.Errorf("host result ports = %+v", scan.Hosts[0].Ports)
+	}
+
+	resp, body = request(t, "GET", ts.URL+"/api/scans/"+scan.ID+"/results?format=csv", "")
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte("10.0.0.2,,22,tcp,open")) {
+		t.Errorf("csv results = %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = request(t, "GET", ts.URL+"/api/scans/"+scan.ID+"/events", "")
+	if resp.StatusCode != http.StatusOK || bytes.Count(body, []byte("event: host")) != 2 || !bytes.Contains(body, []byte(`"status":"completed"`)) {
+		t.Errorf("events = %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = request(t, "GET", ts.URL+"/metrics", "")
+	for _, want := range []string{
+		`chainmap_jobs_completed_total{status="ok"} 2`,
+		`chainmap_open_ports_total{service="ssh"} 2`,
+		`chainmap_nmap_exit_codes_total{code="0"} 2`,
+		"chainmap_jobs_running 0",
+		"chainmap_jobs_queued 0",
+	} {
+		if resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte(want)) {
+			t.Errorf("metrics missing %q: %s", want, body)
+		}
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	ts := newTestServer(t, Config{Token: "test", MaxThreads: 20, MaxTimeout: 60})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"No targets", `{"targets":[]}`, http.StatusBadRequest},
+		{"Flag as target", `{"targets":["-iL/etc/passwd"]}`, http.StatusBadRequest},
+		{"Unknown profile", `{"targets":["10.0.0.1"],"profile":"loud"}`, http.StatusBadRequest},
+		{"Custom flags disabled", `{"targets":["10.0.0.1"],"nmap_flags":"-sT"}`, http.StatusBadRequest},
+		{"Invalid JSON", `{`, http.StatusBadRequest},
+		{"Negative threads", `{"targets":["10.0.0.1"],"threads":-1}`, http.StatusBadRequest},
+		{"Threads above the cap", `{"targets":["10.0.0.1"],"threads":21}`, http.StatusBadRequest},
+		{"Timeout above the cap", `{"targets":["10.0.0.1"],"timeout":61}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := request(t, "POST", ts.URL+"/api/scans", tt.body)
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.status, body)
+			}
+		})
+	}
+
+	custom := newTestServer(t, Config{AllowCustomFlags: true})
+	for _, flags := range []string{"-sT -iL targets.txt", "-sT 10.0.0.0/8", "--script ./evil.nse", "--datadir /tmp/nse", "-sT '"} {
+		body, _ := json.Marshal(ScanRequest{Targets: []string{"10.0.0.1"}, NmapFlags: flags})
+		if resp, out := request(t, "POST", custom.URL+"/api/scans", string(body)); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("nmap_flags %q: status = %d, want %d: %s", flags, resp.StatusCode, http.StatusBadRequest, out)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/api/scans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Result is a tabular query result.
+type Result struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+const openPortsQuery = `
+SELECT s.id AS scan, s.started_at AS scanned, h.address, p.port, p.protocol,
+	COALESCE(sv.name, '') AS service, COALESCE(sv.product, '') AS product, COALESCE(sv.version, '') AS version
+FROM ports p
+JOIN hosts h ON h.id = p.host_id
+JOIN scans s ON s.id = h.scan_id
+LEFT JOIN services sv ON sv.id = p.service_id
+WHERE p.state = 'open'`
+
+// latestOnly restricts a query over hosts h to each address's most recent scan.
+const latestOnly = `
+	AND h.scan_id = (SELECT MAX(h2.scan_id) FROM hosts h2 WHERE h2.address = h.address)`
+
+const orderByHost = `
+ORDER BY s.id, h.address, p.port`
+
+// Scans lists every stored scan with its host and open port counts.
+func (d *DB) Scans() (*Result, error) {
+	return d.Raw(`
+SELECT s.id AS scan, s.started_at, s.finished_at, s.source,
+	(SELECT COUNT(*) FROM hosts h WHERE h.scan_id = s.id) AS hosts,
+	(SELECT COUNT(*) FROM ports p JOIN hosts h ON h.id = p.host_id WHERE h.scan_id = s.id AND p.state = 'open') AS open_ports
+FROM scans s
+ORDER BY s.id`)
+}
+
+// OpenPort returns hosts with port open.
+func (d *DB) OpenPort(port int, latest bool) (*Result, error) {
+	q := openPortsQuery + " AND p.port = ?"
+	if latest {
+		q += latestOnly
+	}
+	return d.Raw(q+orderByHost, port)
+}
+
+// Service returns open ports whose service name matches name.
+func (d *DB) Service(name string, latest bool) (*Result, error) {
+	q := openPortsQuery + " AND sv.name LIKE ?"
+	if latest {
+		q += latestOnly
+	}
+	return d.Raw(q+orderByHost, "%"+name+"%")
+}
+
+// ProductBelow returns open ports running product at a version lower than
+// below, e.g. all Apache older than 2.4.50.
+func (d *DB) ProductBelow(product, below string, latest bool) (*Result, error) {
+	q := openPortsQuery + " AND sv.product LIKE ? AND sv.version != ''"
+	if latest {
+		q += latestOnly
+	}
+	res, err := d.Raw(q+orderByHost, "%"+product+"%")
+	if err != nil || below == "" {
+		return res, err
+	}
+
+	versionCol := len(res.Columns) - 1
+	filtered := res.Rows[:0]
+	for _, row := range res.Rows {
+		if CompareVersions(row[versionCol], below) < 0 {
+			filtered = append(filtered, row)
+		}
+	}
+	res.Rows = filtered
+	return res, nil
+}
+
+// Raw runs an arbitrary SQL query and returns every value as text.
+func (d *DB) Raw(query string, args ...interface{}) (*Result, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Columns: cols, Rows: [][]string{}}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(cols))
+		for i, v := range values {
+			row[i] = formatValue(v)
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, rows.Err()
+}
+
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(val)
+	case sql.RawBytes:
+		return string(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// CompareVersions compares two version strings segment by segment, treating
+// digit runs as numbers, so that "2.4.9" < "2.4.50" and "8.2p1" < "8.9p1".
+// It returns -1, 0 or 1.
+func CompareVersions(a, b string) int {
+	as, bs := versionSegments(a), versionSegments(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareSegment(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+func versionSegments(v string) []string {
+	var segs []string
+	var cur strings.Builder
+	digits := false
+	flush := func() {
+		if cur.Len() > 0 {
+			segs = append(segs, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(v) {
+		switch {
+		case unicode.IsDigit(r):
+			if !digits {
+				flush()
+			}
+			digits = true
+			cur.WriteRune(r)
+		case unicode.IsLetter(r):
+			if digits {
+				flush()
+			}
+			digits = false
+			cur.WriteRune(r)
+		default:
+			flush()
+			digits = false
+		}
+	}
+	flush()
+	return segs
+}
+
+func compareSegment(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		// A numeric segment sorts after a pre-release tag such as "rc".
+		return 1
+	case bErr == nil:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
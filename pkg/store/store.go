@@ -0,0 +1,199 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lair-framework/go-nmap"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS scans (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at   TEXT NOT NULL,
+	finished_at  TEXT NOT NULL,
+	source       TEXT,
+	args         TEXT,
+	nmap_version TEXT
+);
+CREATE TABLE IF NOT EXISTS hosts (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	scan_id   INTEGER NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+	address   TEXT NOT NULL,
+	addr_type TEXT,
+	status    TEXT,
+	reason    TEXT
+);
+CREATE TABLE IF NOT EXISTS hostnames (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	host_id INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	name    TEXT NOT NULL,
+	type    TEXT
+);
+CREATE TABLE IF NOT EXISTS services (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	name       TEXT NOT NULL,
+	product    TEXT NOT NULL,
+	version    TEXT NOT NULL,
+	extra_info TEXT NOT NULL,
+	cpe        TEXT NOT NULL,
+	UNIQUE (name, product, version, extra_info, cpe)
+);
+CREATE TABLE IF NOT EXISTS ports (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	host_id    INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	port       INTEGER NOT NULL,
+	protocol   TEXT NOT NULL,
+	state      TEXT,
+	reason     TEXT,
+	service_id INTEGER REFERENCES services(id)
+);
+CREATE TABLE IF NOT EXISTS script_output (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	host_id   INTEGER NOT NULL REFERENCES hosts(id) ON DELETE CASCADE,
+	port_id   INTEGER REFERENCES ports(id) ON DELETE CASCADE,
+	script_id TEXT NOT NULL,
+	output    TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_hosts_scan ON hosts(scan_id);
+CREATE INDEX IF NOT EXISTS idx_hosts_address ON hosts(address);
+CREATE INDEX IF NOT EXISTS idx_ports_host ON ports(host_id);
+CREATE INDEX IF NOT EXISTS idx_ports_port ON ports(port, state);
+CREATE INDEX IF NOT EXISTS idx_scripts_host ON script_output(host_id);
+`
+
+// DB is a chainmap results database.
+type DB struct {
+	db *sql.DB
+}
+
+// Open opens or creates the SQLite database at path and applies the schema.
+func Open(path string) (*DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply schema: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// SaveScan appends run as a new scan and returns its ID.
+func (d *DB) SaveScan(run *nmap.NmapRun, source string, started, finished time.Time) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO scans (started_at, finished_at, source, args, nmap_version) VALUES (?, ?, ?, ?, ?)`,
+		started.UTC().Format(time.RFC3339), finished.UTC().Format(time.RFC3339), source, run.Args, run.Version)
+	if err != nil {
+		return 0, err
+	}
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, host := range run.Hosts {
+		if err := saveHost(tx, scanID, host); err != nil {
+			return 0, err
+		}
+	}
+
+	return scanID, tx.Commit()
+}
+
+func saveHost(tx *sql.Tx, scanID int64, host nmap.Host) error {
+	address, addrType := "", ""
+	if len(host.Addresses) > 0 {
+		address, addrType = host.Addresses[0].Addr, host.Addresses[0].AddrType
+	}
+
+	res, err := tx.Exec(`INSERT INTO hosts (scan_id, address, addr_type, status, reason) VALUES (?, ?, ?, ?, ?)`,
+		scanID, address, addrType, host.Status.State, host.Status.Reason)
+	if err != nil {
+		return err
+	}
+	hostID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, hn := range host.Hostnames {
+		if _, err := tx.Exec(`INSERT INTO hostnames (host_id, name, type) VALUES (?, ?, ?)`, hostID, hn.Name, hn.Type); err != nil {
+			return err
+		}
+	}
+
+	for _, port := range host.Ports {
+		serviceID, err := serviceID(tx, port.Service)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(`INSERT INTO ports (host_id, port, protocol, state, reason, service_id) VALUES (?, ?, ?, ?, ?, ?)`,
+			hostID, port.PortId, port.Protocol, port.State.State, port.State.Reason, serviceID)
+		if err != nil {
+			return err
+		}
+		portID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, s := range port.Scripts {
+			if _, err := tx.Exec(`INSERT INTO script_output (host_id, port_id, script_id, output) VALUES (?, ?, ?, ?)`,
+				hostID, portID, s.Id, s.Output); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, s := range host.HostScripts {
+		if _, err := tx.Exec(`INSERT INTO script_output (host_id, script_id, output) VALUES (?, ?, ?)`,
+			hostID, s.Id, s.Output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serviceID returns the ID of the matching services row, inserting it first
+// if needed. Ports without service information get a NULL service.
+func serviceID(tx *sql.Tx, s nmap.Service) (sql.NullInt64, error) {
+	cpes := make([]string, 0, len(s.CPEs))
+	for _, c := range s.CPEs {
+		cpes = append(cpes, string(c))
+	}
+	cpe := strings.Join(cpes, " ")
+
+	if s.Name == "" && s.Product == "" {
+		return sql.NullInt64{}, nil
+	}
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO services (name, product, version, extra_info, cpe) VALUES (?, ?, ?, ?, ?)`,
+		s.Name, s.Product, s.Version, s.ExtraInfo, cpe); err != nil {
+		return sql.NullInt64{}, err
+	}
+
+	var id int64
+	err := tx.QueryRow(`SELECT id FROM services WHERE name = ? AND product = ? AND version = ? AND extra_info = ? AND cpe = ?`,
+		s.Name, s.Product, s.Version, s.ExtraInfo, cpe).Scan(&id)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	return sql.NullInt64{Int64: id, Valid: true}, nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lair-framework/go-nmap"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.4.49", "2.4.50", -1},
+		{"2.4.9", "2.4.50", -1},
+		{"2.4.50", "2.4.50", 0},
+		{"8.9p1", "8.2p1", 1},
+		{"1.0rc1", "1.0", 1},
+		{"1.0", "1.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSaveScanAndQuery(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "chainmap.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	run := func(version string) *nmap.NmapRun {
+		return &nmap.NmapRun{Hosts: []nmap.Host{{
+			Status:    nmap.Status{State: "up"},
+			Addresses: []nmap.Address{{Addr: "10.0.0.1", AddrType: "ipv4"}},
+			Ports: []nmap.Port{
+				{PortId: 80, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "http", Product: "Apache httpd", Version: version}},
+				{PortId: 3389, Protocol: "tcp", State: nmap.State{State: "open"},
+					Service: nmap.Service{Name: "ms-wbt-server"}},
+			},
+		}}}
+	}
+
+	now := time.Now()
+	for _, v := range []string{"2.4.49", "2.4.54"} {
+		if _, err := db.SaveScan(run(v), "test", now, now); err != nil {
+			t.Fatalf("SaveScan() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query func() (*Result, error)
+		want  int
+	}{
+		{"scans", db.Scans, 2},
+		{"open port", func() (*Result, error) { return db.OpenPort(3389, false) }, 2},
+		{"open port latest", func() (*Result, error) { return db.OpenPort(3389, true) }, 1},
+		{"product below", func() (*Result, error) { return db.ProductBelow("Apache", "2.4.50", false) }, 1},
+		{"product below latest", func() (*Result, error) { return db.ProductBelow("Apache", "2.4.50", true) }, 0},
+		{"service", func() (*Result, error) { return db.Service("http", false) }, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.query()
+			if err != nil {
+				t.Fatalf("query error = %v", err)
+			}
+			if len(res.Rows) != tt.want {
+				t.Errorf("got %d rows, want %d: %v", len(res.Rows), tt.want, res.Rows)
+			}
+		})
+	}
+}